@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/johnfernkas/mosaic-addon/internal/apps"
+	"github.com/johnfernkas/mosaic-addon/internal/config"
 	"github.com/johnfernkas/mosaic-addon/internal/server"
 )
 
@@ -20,6 +25,19 @@ func main() {
 		dataDir = "/data"
 	}
 
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "refresh-index":
+			if err := refreshIndex(dataDir); err != nil {
+				log.Fatalf("Refreshing community index failed: %v", err)
+			}
+		default:
+			log.Fatalf("Unknown command %q (available: refresh-index)", os.Args[1])
+		}
+		return
+	}
+
 	log.Println("🎨 Mosaic - LED Matrix Display Server")
 	log.Printf("Port: %s, Data: %s", port, dataDir)
 
@@ -37,3 +55,23 @@ func main() {
 		log.Fatalf("Server failed: %v", err)
 	}
 }
+
+// refreshIndex rebuilds the community index from the configured community
+// source. A running server picks it up on restart, or refreshes itself via
+// POST /api/apps/community/refresh.
+func refreshIndex(dataDir string) error {
+	cfg, err := config.Load(filepath.Join(dataDir, "config.json"))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	index, err := apps.RefreshCommunityIndex(ctx, dataDir, apps.SourceFromConfig(cfg.CommunitySource))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Community index refreshed: %d apps\n", index.Count)
+	return nil
+}
@@ -4,5 +4,8 @@ go 1.22
 
 require (
 	github.com/go-chi/chi/v5 v5.0.11
+	github.com/gorilla/websocket v1.5.1
+	github.com/tidbyt/go-libwebp v0.0.0-20230922075150-fb11063b2a6a
+	gopkg.in/yaml.v3 v3.0.1
 	tidbyt.dev/pixlet v0.33.3
 )
@@ -0,0 +1,484 @@
+package apps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/johnfernkas/mosaic-addon/internal/config"
+)
+
+// Source is where community apps come from: the Tidbyt community repository,
+// a fork of it (e.g. Tronbyt) or a self-hosted mirror with a GitHub-style API
+type Source struct {
+	APIURL  string `json:"api_url,omitempty"` // API base, e.g. https://api.github.com or https://gitea.local/api/v1
+	RawURL  string `json:"raw_url,omitempty"` // file URL template; {repo}, {branch} and {path} are filled in
+	Repo    string `json:"repo,omitempty"`    // owner/name
+	Branch  string `json:"branch,omitempty"`
+	AppsDir string `json:"apps_dir,omitempty"` // directory holding one directory per app
+	Token   string `json:"token,omitempty"`    // sent to the API, for private forks and rate limits
+}
+
+// DefaultSource is the upstream Tidbyt community repository
+var DefaultSource = Source{
+	APIURL:  "https://api.github.com",
+	RawURL:  "https://raw.githubusercontent.com/{repo}/{branch}/{path}",
+	Repo:    "tidbyt/community",
+	Branch:  "main",
+	AppsDir: "apps",
+}
+
+// SourceFromConfig returns the source configured by cfg
+func SourceFromConfig(cfg config.CommunitySource) Source {
+	return Source(cfg)
+}
+
+// communityIndexFile is the name of the community index in the data dirs
+const communityIndexFile = "community-apps.json"
+
+// sourceClient talks to community sources. Its timeout keeps a stalled
+// connection from hanging an install or update.
+var sourceClient = &http.Client{Timeout: 30 * time.Second}
+
+// bundledIndexPath is the community index bundled in the Docker image (in
+// /app/data, not /data which is a volume mount)
+var bundledIndexPath = "/app/data/" + communityIndexFile
+
+// withDefaults fills unset fields from DefaultSource
+func (s Source) withDefaults() Source {
+	if s.APIURL == "" {
+		s.APIURL = DefaultSource.APIURL
+	}
+	if s.RawURL == "" {
+		s.RawURL = DefaultSource.RawURL
+	}
+	if s.Repo == "" {
+		s.Repo = DefaultSource.Repo
+	}
+	if s.Branch == "" {
+		s.Branch = DefaultSource.Branch
+	}
+	if s.AppsDir == "" {
+		s.AppsDir = DefaultSource.AppsDir
+	}
+	s.APIURL = strings.TrimSuffix(s.APIURL, "/")
+	s.AppsDir = strings.Trim(s.AppsDir, "/")
+	return s
+}
+
+// contentsURL returns the API URL listing a directory of the apps dir
+func (s Source) contentsURL(dir string) string {
+	return fmt.Sprintf("%s/repos/%s/contents/%s?ref=%s",
+		s.APIURL, s.Repo, path.Join(s.AppsDir, dir), url.QueryEscape(s.Branch))
+}
+
+// treeURL returns the API URL listing every file on the branch
+func (s Source) treeURL() string {
+	return fmt.Sprintf("%s/repos/%s/git/trees/%s?recursive=1", s.APIURL, s.Repo, url.PathEscape(s.Branch))
+}
+
+// fileURL returns the URL to download a file of the apps dir from
+func (s Source) fileURL(file string) string {
+	return strings.NewReplacer(
+		"{repo}", s.Repo,
+		"{branch}", s.Branch,
+		"{path}", path.Join(s.AppsDir, file),
+	).Replace(s.RawURL)
+}
+
+// fetch GETs a URL from the source and returns the body. API requests carry
+// the token.
+func (s Source) fetch(ctx context.Context, client *http.Client, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.Token != "" && strings.HasPrefix(rawURL, s.APIURL) {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", rawURL, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// treeEntry is a file ("blob") or directory ("tree") in a source listing
+type treeEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+	SHA  string `json:"sha"`
+}
+
+// fetchTree lists every file on the source's branch. truncated is set when
+// the listing was too big to return in full.
+func (s Source) fetchTree(ctx context.Context, client *http.Client) (entries []treeEntry, truncated bool, err error) {
+	data, err := s.fetch(ctx, client, s.treeURL())
+	if err != nil {
+		return nil, false, fmt.Errorf("listing source tree: %w", err)
+	}
+
+	var tree struct {
+		Truncated bool        `json:"truncated"`
+		Tree      []treeEntry `json:"tree"`
+	}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, false, fmt.Errorf("parsing source tree: %w", err)
+	}
+	return tree.Tree, tree.Truncated, nil
+}
+
+// FetchCommunityIndex builds a community index from the apps in a source's
+// tree. Apps only get an ID, a name made from it and their main .star file.
+func FetchCommunityIndex(ctx context.Context, client *http.Client, src Source) (*CommunityIndex, error) {
+	src = src.withDefaults()
+
+	entries, truncated, err := src.fetchTree(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	var apps []CommunityApp
+	if truncated {
+		// Too big for one listing; the apps dir alone has the IDs
+		log.Printf("Source tree listing truncated, listing %s only", src.AppsDir)
+		dirs, err := listAppDirs(ctx, client, src)
+		if err != nil {
+			return nil, err
+		}
+		for _, dir := range dirs {
+			apps = append(apps, CommunityApp{ID: dir.Path, Name: displayName(dir.Path)})
+		}
+	} else {
+		starFiles := make(map[string][]string)
+		for _, entry := range entries {
+			rest, ok := strings.CutPrefix(entry.Path, src.AppsDir+"/")
+			if !ok || entry.Type != "blob" {
+				continue
+			}
+			id, file, ok := strings.Cut(rest, "/")
+			if ok && !strings.Contains(file, "/") && strings.HasSuffix(file, ".star") {
+				starFiles[id] = append(starFiles[id], file)
+			}
+		}
+		for id, files := range starFiles {
+			apps = append(apps, CommunityApp{
+				ID:       id,
+				Name:     displayName(id),
+				FileName: mainStarFile(id, files),
+			})
+		}
+	}
+
+	sort.Slice(apps, func(i, j int) bool { return apps[i].ID < apps[j].ID })
+	return &CommunityIndex{
+		Updated: time.Now().UTC(),
+		Count:   len(apps),
+		Apps:    apps,
+	}, nil
+}
+
+// listAppDirs lists the app directories of a source, with Path set to the
+// app ID
+func listAppDirs(ctx context.Context, client *http.Client, src Source) ([]treeEntry, error) {
+	data, err := src.fetch(ctx, client, src.contentsURL(""))
+	if err != nil {
+		return nil, fmt.Errorf("listing apps directory: %w", err)
+	}
+
+	var entries []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+		SHA  string `json:"sha"`
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parsing apps directory: %w", err)
+	}
+
+	var dirs []treeEntry
+	for _, e := range entries {
+		if e.Type == "dir" {
+			dirs = append(dirs, treeEntry{Path: e.Name, Type: "tree", SHA: e.SHA})
+		}
+	}
+	return dirs, nil
+}
+
+// fetchAppVersions returns the version of every app in a source: the git
+// tree SHA of its directory, which changes whenever any of its files do
+func fetchAppVersions(ctx context.Context, client *http.Client, src Source) (map[string]string, error) {
+	entries, truncated, err := src.fetchTree(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	if truncated {
+		if entries, err = listAppDirs(ctx, client, src); err != nil {
+			return nil, err
+		}
+	} else {
+		var dirs []treeEntry
+		for _, entry := range entries {
+			id, ok := strings.CutPrefix(entry.Path, src.AppsDir+"/")
+			if ok && entry.Type == "tree" && !strings.Contains(id, "/") {
+				dirs = append(dirs, treeEntry{Path: id, Type: entry.Type, SHA: entry.SHA})
+			}
+		}
+		entries = dirs
+	}
+
+	versions := make(map[string]string, len(entries))
+	for _, dir := range entries {
+		versions[dir.Path] = dir.SHA
+	}
+	return versions, nil
+}
+
+// fetchAppVersion returns the version of one app in a source, or "" if the
+// source doesn't have it. Listing the apps dir is enough, unless the app is
+// past the end of a listing cut short.
+func fetchAppVersion(ctx context.Context, client *http.Client, src Source, id string) (string, error) {
+	dirs, err := listAppDirs(ctx, client, src)
+	if err != nil {
+		return "", err
+	}
+	for _, dir := range dirs {
+		if dir.Path == id {
+			return dir.SHA, nil
+		}
+	}
+
+	versions, err := fetchAppVersions(ctx, client, src)
+	if err != nil {
+		return "", err
+	}
+	return versions[id], nil
+}
+
+// downloadApp downloads an app's whole directory from the source into dir,
+// keeping its layout, and returns the path of its main .star file
+func downloadApp(ctx context.Context, client *http.Client, src Source, id, dir string) (string, error) {
+	log.Printf("Downloading app %s from: %s", id, src.fileURL(id))
+
+	var starFiles []string
+	if err := downloadDir(ctx, client, src, id, "", dir, &starFiles); err != nil {
+		return "", err
+	}
+	if len(starFiles) == 0 {
+		return "", fmt.Errorf("no .star file found in app %s", id)
+	}
+
+	// The manifest names the main file, if the app has one
+	if manifest, _ := readManifest(dir); manifest != nil && slices.Contains(starFiles, manifest.FileName) {
+		return filepath.Join(dir, manifest.FileName), nil
+	}
+	return filepath.Join(dir, mainStarFile(id, starFiles)), nil
+}
+
+// downloadDir downloads the directory rel of an app and everything below it.
+// The .star files at the top of the app are added to starFiles.
+func downloadDir(ctx context.Context, client *http.Client, src Source, id, rel, dir string, starFiles *[]string) error {
+	listing, err := src.fetch(ctx, client, src.contentsURL(path.Join(id, rel)))
+	if err != nil {
+		return fmt.Errorf("listing app directory: %w", err)
+	}
+
+	var entries []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(listing, &entries); err != nil {
+		return fmt.Errorf("parsing directory listing: %w", err)
+	}
+
+	for _, e := range entries {
+		file := path.Join(rel, e.Name)
+		if !filepath.IsLocal(file) || strings.Contains(e.Name, "/") {
+			return fmt.Errorf("app %s has an invalid file name %q", id, e.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(file))
+
+		switch e.Type {
+		case "dir":
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("creating app directory: %w", err)
+			}
+			if err := downloadDir(ctx, client, src, id, file, dir, starFiles); err != nil {
+				return err
+			}
+		case "file":
+			data, err := src.fetch(ctx, client, src.fileURL(path.Join(id, file)))
+			if err != nil {
+				return fmt.Errorf("downloading %s: %w", file, err)
+			}
+			if err := os.WriteFile(target, data, 0644); err != nil {
+				return fmt.Errorf("writing %s: %w", file, err)
+			}
+			if rel == "" && strings.HasSuffix(e.Name, ".star") {
+				*starFiles = append(*starFiles, e.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// mainStarFile picks the applet among an app's .star files: the one named
+// after the app, else the first
+func mainStarFile(id string, files []string) string {
+	sort.Strings(files)
+	for _, f := range files {
+		name := strings.ReplaceAll(strings.TrimSuffix(f, ".star"), "_", "")
+		if strings.EqualFold(name, id) {
+			return f
+		}
+	}
+	return files[0]
+}
+
+// displayName makes a name from an app ID: "OctoWatts" -> "Octo Watts",
+// "3cellularautomata" -> "3 Cellularautomata"
+func displayName(id string) string {
+	var words []string
+	var word []rune
+	prev := rune(0)
+	for _, c := range id {
+		split := c == '_' || c == '-' ||
+			(unicode.IsUpper(c) && unicode.IsLower(prev)) ||
+			(unicode.IsLetter(c) && unicode.IsDigit(prev)) ||
+			(unicode.IsDigit(c) && unicode.IsLetter(prev))
+		if split && len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+		if c != '_' && c != '-' {
+			if len(word) == 0 {
+				c = unicode.ToUpper(c)
+			}
+			word = append(word, c)
+		}
+		prev = c
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return strings.Join(words, " ")
+}
+
+// RefreshCommunityIndex fetches a new community index from src and saves it
+// in dataDir, where it takes precedence over the bundled one. Summaries and
+// the like already known for an app, from the saved or bundled index, are
+// kept.
+func RefreshCommunityIndex(ctx context.Context, dataDir string, src Source) (*CommunityIndex, error) {
+	return refreshCommunityIndex(ctx, dataDir, src, savedIndexes(dataDir)...)
+}
+
+// refreshCommunityIndex fetches and saves a new community index, filling in
+// what the listing doesn't have from the known indexes, earlier ones first
+func refreshCommunityIndex(ctx context.Context, dataDir string, src Source, known ...*CommunityIndex) (*CommunityIndex, error) {
+	index, err := FetchCommunityIndex(ctx, sourceClient, src)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, old := range known {
+		if old == nil {
+			continue
+		}
+		prev := make(map[string]CommunityApp, len(old.Apps))
+		for _, app := range old.Apps {
+			prev[app.ID] = app
+		}
+		for i := range index.Apps {
+			app, p := &index.Apps[i], prev[index.Apps[i].ID]
+			app.Summary = orElse(app.Summary, p.Summary)
+			app.Author = orElse(app.Author, p.Author)
+			app.Category = orElse(app.Category, p.Category)
+		}
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshaling community index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, communityIndexFile), data, 0644); err != nil {
+		return nil, fmt.Errorf("writing community index: %w", err)
+	}
+
+	log.Printf("Refreshed community index from %s: %d apps", src.withDefaults().Repo, index.Count)
+	return index, nil
+}
+
+// RefreshCommunityIndex fetches a new community index from the repository's
+// source, saves it and starts using it
+func (r *Repository) RefreshCommunityIndex(ctx context.Context) (*CommunityIndex, error) {
+	r.mu.RLock()
+	src := r.source
+	known := append([]*CommunityIndex{r.communityIndex}, savedIndexes(r.dataDir)...)
+	r.mu.RUnlock()
+
+	index, err := refreshCommunityIndex(ctx, r.dataDir, src, known...)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.communityIndex = index
+	r.mu.Unlock()
+	return index, nil
+}
+
+// SetSource sets where community apps are installed and indexed from
+func (r *Repository) SetSource(src Source) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.source = src.withDefaults()
+}
+
+// savedIndexes returns the community indexes saved in dataDir and bundled in
+// the image, those that can be read
+func savedIndexes(dataDir string) []*CommunityIndex {
+	var indexes []*CommunityIndex
+	for _, path := range []string{filepath.Join(dataDir, communityIndexFile), bundledIndexPath} {
+		if index, err := readCommunityIndex(path); err == nil {
+			indexes = append(indexes, index)
+		}
+	}
+	return indexes
+}
+
+func readCommunityIndex(path string) (*CommunityIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var index CommunityIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("parsing community index: %w", err)
+	}
+	return &index, nil
+}
+
+func orElse(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
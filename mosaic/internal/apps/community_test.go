@@ -0,0 +1,361 @@
+package apps
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeSource serves a repository of files through a GitHub-style API
+type fakeSource struct {
+	files     map[string]string // path -> content
+	listings  map[string]string // directory -> raw contents listing, overriding files
+	truncated bool              // the tree listing is cut short
+	trees     atomic.Int32      // tree listings served
+}
+
+// newFakeSource starts a server for the files and returns a source using it
+func newFakeSource(t *testing.T, files map[string]string) (*fakeSource, Source) {
+	t.Helper()
+	f := &fakeSource{files: files, listings: make(map[string]string)}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	return f, Source{
+		APIURL:  srv.URL + "/api",
+		RawURL:  srv.URL + "/raw/{repo}/{branch}/{path}",
+		Repo:    "owner/community",
+		Branch:  "main",
+		AppsDir: "apps",
+	}
+}
+
+func (f *fakeSource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const api = "/api/repos/owner/community/"
+	switch p := r.URL.Path; {
+	case p == api+"git/trees/main" && r.URL.Query().Get("recursive") == "1":
+		f.trees.Add(1)
+		f.serveTree(w)
+	case strings.HasPrefix(p, api+"contents/") && r.URL.Query().Get("ref") == "main":
+		f.serveContents(w, strings.TrimPrefix(p, api+"contents/"))
+	case strings.HasPrefix(p, "/raw/owner/community/main/"):
+		content, ok := f.files[strings.TrimPrefix(p, "/raw/owner/community/main/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, content)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeSource) serveTree(w http.ResponseWriter) {
+	var tree []treeEntry
+	dirs := make(map[string]bool)
+	for file := range f.files {
+		tree = append(tree, treeEntry{Path: file, Type: "blob", SHA: f.sha(file)})
+		for dir := path.Dir(file); dir != "." && !dirs[dir]; dir = path.Dir(dir) {
+			dirs[dir] = true
+			tree = append(tree, treeEntry{Path: dir, Type: "tree", SHA: f.sha(dir)})
+		}
+	}
+	sort.Slice(tree, func(i, j int) bool { return tree[i].Path < tree[j].Path })
+
+	if f.truncated {
+		tree = tree[:1]
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"truncated": f.truncated,
+		"tree":      tree,
+	})
+}
+
+func (f *fakeSource) serveContents(w http.ResponseWriter, dir string) {
+	if listing, ok := f.listings[dir]; ok {
+		fmt.Fprint(w, listing)
+		return
+	}
+
+	type entry struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+		SHA  string `json:"sha"`
+	}
+	seen := make(map[string]bool)
+	entries := []entry{}
+	for file := range f.files {
+		rest, ok := strings.CutPrefix(file, dir+"/")
+		if !ok {
+			continue
+		}
+		name, _, isDir := strings.Cut(rest, "/")
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		e := entry{Name: name, Type: "file", SHA: f.sha(path.Join(dir, name))}
+		if isDir {
+			e.Type = "dir"
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) == 0 {
+		http.NotFound(w, nil)
+		return
+	}
+	json.NewEncoder(w).Encode(entries)
+}
+
+// sha hashes a file or directory from the paths and contents of its files,
+// so it changes whenever any of them do
+func (f *fakeSource) sha(p string) string {
+	var files []string
+	for file := range f.files {
+		if file == p || strings.HasPrefix(file, p+"/") {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+
+	h := sha1.New()
+	for _, file := range files {
+		fmt.Fprintf(h, "%s\x00%s\x00", file, f.files[file])
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// testFiles is a small community repository
+func testFiles() map[string]string {
+	return map[string]string{
+		"README.md":                        "# Community",
+		"apps/README.md":                   "One directory per app",
+		"apps/clock/clock.star":            "def main(config): pass",
+		"apps/clock/images/icon.png":       "\x89PNG",
+		"apps/clock/images/small/dot.png":  "\x89PNG.",
+		"apps/weatherapp/helper.star":      "def helper(): pass",
+		"apps/weatherapp/weather_app.star": "def main(config): pass",
+		"apps/multi/a.star":                "def main(config): pass",
+		"apps/multi/b.star":                "def main(config): pass",
+		"apps/multi/manifest.yaml":         "id: multi\nname: Multi\nfileName: b.star\n",
+	}
+}
+
+func TestFetchCommunityIndex(t *testing.T) {
+	_, src := newFakeSource(t, testFiles())
+
+	index, err := FetchCommunityIndex(context.Background(), http.DefaultClient, src)
+	if err != nil {
+		t.Fatalf("FetchCommunityIndex: %v", err)
+	}
+
+	want := []CommunityApp{
+		{ID: "clock", Name: "Clock", FileName: "clock.star"},
+		{ID: "multi", Name: "Multi", FileName: "a.star"},
+		{ID: "weatherapp", Name: "Weatherapp", FileName: "weather_app.star"},
+	}
+	if !reflect.DeepEqual(index.Apps, want) {
+		t.Errorf("apps = %+v, want %+v", index.Apps, want)
+	}
+	if index.Count != len(want) {
+		t.Errorf("count = %d, want %d", index.Count, len(want))
+	}
+}
+
+func TestFetchCommunityIndexTruncated(t *testing.T) {
+	f, src := newFakeSource(t, testFiles())
+	f.truncated = true
+
+	index, err := FetchCommunityIndex(context.Background(), http.DefaultClient, src)
+	if err != nil {
+		t.Fatalf("FetchCommunityIndex: %v", err)
+	}
+
+	// Only the apps dir is listed, so the main files aren't known
+	want := []CommunityApp{
+		{ID: "clock", Name: "Clock"},
+		{ID: "multi", Name: "Multi"},
+		{ID: "weatherapp", Name: "Weatherapp"},
+	}
+	if !reflect.DeepEqual(index.Apps, want) {
+		t.Errorf("apps = %+v, want %+v", index.Apps, want)
+	}
+}
+
+func TestFetchAppVersions(t *testing.T) {
+	for _, truncated := range []bool{false, true} {
+		f, src := newFakeSource(t, testFiles())
+		f.truncated = truncated
+
+		versions, err := fetchAppVersions(context.Background(), http.DefaultClient, src)
+		if err != nil {
+			t.Fatalf("truncated=%v: fetchAppVersions: %v", truncated, err)
+		}
+		want := map[string]string{
+			"clock":      f.sha("apps/clock"),
+			"multi":      f.sha("apps/multi"),
+			"weatherapp": f.sha("apps/weatherapp"),
+		}
+		if !reflect.DeepEqual(versions, want) {
+			t.Errorf("truncated=%v: versions = %v, want %v", truncated, versions, want)
+		}
+
+		// A change to a nested file is a new version of the app
+		f.files["apps/clock/images/small/dot.png"] = "\x89PNG!"
+		versions, err = fetchAppVersions(context.Background(), http.DefaultClient, src)
+		if err != nil {
+			t.Fatalf("truncated=%v: fetchAppVersions: %v", truncated, err)
+		}
+		if versions["clock"] == want["clock"] || versions["multi"] != want["multi"] {
+			t.Errorf("truncated=%v: versions after change = %v", truncated, versions)
+		}
+	}
+}
+
+func TestFetchAppVersion(t *testing.T) {
+	f, src := newFakeSource(t, testFiles())
+	src = src.withDefaults()
+
+	version, err := fetchAppVersion(context.Background(), http.DefaultClient, src, "clock")
+	if err != nil {
+		t.Fatalf("fetchAppVersion: %v", err)
+	}
+	if want := f.sha("apps/clock"); version != want {
+		t.Errorf("version = %s, want %s", version, want)
+	}
+	if n := f.trees.Load(); n != 0 {
+		t.Errorf("tree listed %d times for one app, want 0", n)
+	}
+
+	// Apps missing from the listing are looked up in the tree
+	f.listings["apps"] = `[{"name": "multi", "type": "dir", "sha": "x"}]`
+	version, err = fetchAppVersion(context.Background(), http.DefaultClient, src, "clock")
+	if err != nil {
+		t.Fatalf("fetchAppVersion: %v", err)
+	}
+	if want := f.sha("apps/clock"); version != want {
+		t.Errorf("version from the tree = %s, want %s", version, want)
+	}
+
+	version, err = fetchAppVersion(context.Background(), http.DefaultClient, src, "gone")
+	if err != nil || version != "" {
+		t.Errorf("version of a missing app = %q, %v; want none", version, err)
+	}
+}
+
+func TestDownloadApp(t *testing.T) {
+	files := testFiles()
+	_, src := newFakeSource(t, files)
+	src = src.withDefaults()
+
+	tests := []struct {
+		id       string
+		wantMain string
+	}{
+		{"clock", "clock.star"},
+		{"weatherapp", "weather_app.star"}, // named after the app
+		{"multi", "b.star"},                // named by the manifest
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		mainFile, err := downloadApp(context.Background(), http.DefaultClient, src, tt.id, dir)
+		if err != nil {
+			t.Fatalf("%s: downloadApp: %v", tt.id, err)
+		}
+		if want := filepath.Join(dir, tt.wantMain); mainFile != want {
+			t.Errorf("%s: main file = %s, want %s", tt.id, mainFile, want)
+		}
+
+		// Every file of the app, nested ones included, and nothing else
+		got := make(map[string]string)
+		filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				data, _ := os.ReadFile(p)
+				rel, _ := filepath.Rel(dir, p)
+				got[filepath.ToSlash(rel)] = string(data)
+			}
+			return err
+		})
+		want := make(map[string]string)
+		for file, content := range files {
+			if rel, ok := strings.CutPrefix(file, "apps/"+tt.id+"/"); ok {
+				want[rel] = content
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: files = %v, want %v", tt.id, got, want)
+		}
+	}
+}
+
+func TestDownloadDirRejectsEscapingNames(t *testing.T) {
+	f, src := newFakeSource(t, testFiles())
+	src = src.withDefaults()
+
+	for _, name := range []string{"..", "../evil.star", "/etc/passwd"} {
+		f.listings["apps/clock"] = fmt.Sprintf(`[{"name": %q, "type": "file"}]`, name)
+
+		var starFiles []string
+		err := downloadDir(context.Background(), http.DefaultClient, src, "clock", "", t.TempDir(), &starFiles)
+		if err == nil || !strings.Contains(err.Error(), "invalid file name") {
+			t.Errorf("%q: err = %v, want an invalid file name error", name, err)
+		}
+	}
+}
+
+func TestRefreshCommunityIndexKeepsKnownApps(t *testing.T) {
+	_, src := newFakeSource(t, testFiles())
+	dataDir := t.TempDir()
+
+	writeIndex := func(p string, apps ...CommunityApp) {
+		data, _ := json.Marshal(CommunityIndex{Updated: time.Now(), Count: len(apps), Apps: apps})
+		if err := os.WriteFile(p, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The bundled index knows the clock, a saved one the weather app
+	bundled := filepath.Join(t.TempDir(), communityIndexFile)
+	writeIndex(bundled, CommunityApp{ID: "clock", Summary: "Shows the time", Author: "tidbyt", Category: "time"})
+	writeIndex(filepath.Join(dataDir, communityIndexFile), CommunityApp{ID: "weatherapp", Summary: "Shows the weather"})
+	defer func(p string) { bundledIndexPath = p }(bundledIndexPath)
+	bundledIndexPath = bundled
+
+	index, err := RefreshCommunityIndex(context.Background(), dataDir, src)
+	if err != nil {
+		t.Fatalf("RefreshCommunityIndex: %v", err)
+	}
+
+	summaries := make(map[string]string)
+	for _, app := range index.Apps {
+		summaries[app.ID] = app.Summary + "/" + app.Author + "/" + app.Category
+	}
+	want := map[string]string{
+		"clock":      "Shows the time/tidbyt/time",
+		"multi":      "//",
+		"weatherapp": "Shows the weather//",
+	}
+	if !reflect.DeepEqual(summaries, want) {
+		t.Errorf("summaries = %v, want %v", summaries, want)
+	}
+
+	saved, err := readCommunityIndex(filepath.Join(dataDir, communityIndexFile))
+	if err != nil {
+		t.Fatalf("reading saved index: %v", err)
+	}
+	if !reflect.DeepEqual(saved.Apps, index.Apps) {
+		t.Errorf("saved apps = %+v, want %+v", saved.Apps, index.Apps)
+	}
+}
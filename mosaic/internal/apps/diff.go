@@ -0,0 +1,255 @@
+package apps
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Lines of context around each change, as in diff -u
+const diffContext = 3
+
+// Largest changed region aligned line by line; beyond it the region is
+// shown as removed and re-added
+const maxDiffCells = 4_000_000
+
+// Marks a last line without a newline, as in diff -u
+const noNewline = `\ No newline at end of file`
+
+type diffLine struct {
+	op   byte // ' ' unchanged, '-' removed, '+' added
+	text string
+}
+
+// unifiedDiff returns a unified diff between two versions of a file, or ""
+// if they are the same
+func unifiedDiff(oldName, newName string, oldSrc, newSrc []byte) string {
+	lines := diffLines(splitLines(oldSrc), splitLines(newSrc))
+
+	// Line numbers in the old and new file at each line of the diff
+	oldNum := make([]int, len(lines)+1)
+	newNum := make([]int, len(lines)+1)
+	oldNum[0], newNum[0] = 1, 1
+	for i, l := range lines {
+		oldNum[i+1], newNum[i+1] = oldNum[i], newNum[i]
+		if l.op != '+' {
+			oldNum[i+1]++
+		}
+		if l.op != '-' {
+			newNum[i+1]++
+		}
+	}
+
+	var sb strings.Builder
+	for start := 0; start < len(lines); {
+		// Next change
+		first := start
+		for first < len(lines) && lines[first].op == ' ' {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+
+		// Changes closer than twice the context share a hunk
+		from := max(first-diffContext, start)
+		to := first
+		for {
+			for to < len(lines) && lines[to].op != ' ' {
+				to++
+			}
+			next := to
+			for next < len(lines) && lines[next].op == ' ' {
+				next++
+			}
+			if next == len(lines) || next-to > 2*diffContext {
+				to = min(to+diffContext, len(lines))
+				break
+			}
+			to = next
+		}
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n",
+			hunkRange(oldNum[from], oldNum[to]-oldNum[from]),
+			hunkRange(newNum[from], newNum[to]-newNum[from]))
+		for _, l := range lines[from:to] {
+			sb.WriteByte(l.op)
+			sb.WriteString(l.text)
+			sb.WriteByte('\n')
+		}
+		start = to
+	}
+	return sb.String()
+}
+
+// diffAppDirs returns a unified diff of every file that differs between two
+// versions of an app directory, labelled with oldLabel/ and newLabel/.
+// Mosaic's own app.json and config.json are left out.
+func diffAppDirs(oldDir, newDir, oldLabel, newLabel string) string {
+	oldFiles := appFiles(oldDir)
+	newFiles := appFiles(newDir)
+
+	names := make([]string, 0, len(oldFiles)+len(newFiles))
+	for name := range oldFiles {
+		names = append(names, name)
+	}
+	for name := range newFiles {
+		if _, ok := oldFiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		oldSrc, inOld := oldFiles[name]
+		newSrc, inNew := newFiles[name]
+		if inOld && inNew && bytes.Equal(oldSrc, newSrc) {
+			continue
+		}
+
+		oldName, newName := oldLabel+"/"+name, newLabel+"/"+name
+		if !inOld {
+			oldName = "/dev/null"
+		}
+		if !inNew {
+			newName = "/dev/null"
+		}
+		if isBinary(oldSrc) || isBinary(newSrc) {
+			fmt.Fprintf(&sb, "Binary files %s and %s differ\n", oldName, newName)
+			continue
+		}
+		sb.WriteString(unifiedDiff(oldName, newName, oldSrc, newSrc))
+	}
+	return sb.String()
+}
+
+// appFiles reads the files of an app directory, keyed by slash-separated
+// path
+func appFiles(dir string) map[string][]byte {
+	files := make(map[string][]byte)
+	filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil || rel == "app.json" || rel == "config.json" {
+			return nil
+		}
+		if data, err := os.ReadFile(file); err == nil {
+			files[filepath.ToSlash(rel)] = data
+		}
+		return nil
+	})
+	return files
+}
+
+// isBinary guesses whether a file is binary the way git does: by a NUL byte
+// near its start
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// An empty range names the line before it
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits a file into lines. A last line without a newline carries
+// diff's marker, so it differs from the same line with one.
+func splitLines(src []byte) []string {
+	if len(src) == 0 {
+		return nil
+	}
+	s := string(src)
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	if !strings.HasSuffix(s, "\n") {
+		lines[len(lines)-1] += "\n" + noNewline
+	}
+	return lines
+}
+
+// diffLines aligns two files line by line along their longest common
+// subsequence
+func diffLines(a, b []string) []diffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var out []diffLine
+	for _, l := range a[:prefix] {
+		out = append(out, diffLine{' ', l})
+	}
+	out = append(out, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, l := range a[len(a)-suffix:] {
+		out = append(out, diffLine{' ', l})
+	}
+	return out
+}
+
+func diffMiddle(a, b []string) []diffLine {
+	var out []diffLine
+	if len(a)*len(b) > maxDiffCells {
+		for _, l := range a {
+			out = append(out, diffLine{'-', l})
+		}
+		for _, l := range b {
+			out = append(out, diffLine{'+', l})
+		}
+		return out
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, diffLine{'-', a[i]})
+			i++
+		default:
+			out = append(out, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		out = append(out, diffLine{'+', b[j]})
+	}
+	return out
+}
@@ -0,0 +1,201 @@
+package apps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// numbered returns the lines first to last, each its two-digit number, with
+// changes swapped in
+func numbered(first, last int, changes map[int]string) string {
+	var sb strings.Builder
+	for i := first; i <= last; i++ {
+		line, ok := changes[i]
+		if !ok {
+			line = fmt.Sprintf("%02d", i)
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "same",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: "",
+		},
+		{
+			name: "single hunk",
+			old:  numbered(1, 10, nil),
+			new:  numbered(1, 10, map[int]string{5: "five"}),
+			want: `--- old
++++ new
+@@ -2,7 +2,7 @@
+ 02
+ 03
+ 04
+-05
++five
+ 06
+ 07
+ 08
+`,
+		},
+		{
+			// Changes up to twice the context apart share a hunk, further
+			// ones get their own
+			name: "nearby hunks merged",
+			old:  numbered(1, 20, nil),
+			new:  numbered(1, 20, map[int]string{3: "three", 9: "nine", 18: "eighteen"}),
+			want: `--- old
++++ new
+@@ -1,12 +1,12 @@
+ 01
+ 02
+-03
++three
+ 04
+ 05
+ 06
+ 07
+ 08
+-09
++nine
+ 10
+ 11
+ 12
+@@ -15,6 +15,6 @@
+ 15
+ 16
+ 17
+-18
++eighteen
+ 19
+ 20
+`,
+		},
+		{
+			name: "lines added and removed",
+			old:  "a\nb\nc\n",
+			new:  "a\nc\nd\ne\n",
+			want: `--- old
++++ new
+@@ -1,3 +1,4 @@
+ a
+-b
+ c
++d
++e
+`,
+		},
+		{
+			name: "added file",
+			old:  "",
+			new:  "x\ny\n",
+			want: `--- old
++++ new
+@@ -0,0 +1,2 @@
++x
++y
+`,
+		},
+		{
+			name: "deleted file",
+			old:  "x\n",
+			new:  "",
+			want: `--- old
++++ new
+@@ -1,1 +0,0 @@
+-x
+`,
+		},
+		{
+			name: "missing trailing newline",
+			old:  "a\nb\n",
+			new:  "a\nb",
+			want: `--- old
++++ new
+@@ -1,2 +1,2 @@
+ a
+-b
++b
+\ No newline at end of file
+`,
+		},
+		{
+			name: "trailing newline added",
+			old:  "a",
+			new:  "a\n",
+			want: `--- old
++++ new
+@@ -1,1 +1,1 @@
+-a
+\ No newline at end of file
++a
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		got := unifiedDiff("old", "new", []byte(tt.old), []byte(tt.new))
+		if got != tt.want {
+			t.Errorf("%s: diff =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDiffAppDirs(t *testing.T) {
+	write := func(dir string, files map[string]string) {
+		for name, content := range files {
+			file := filepath.Join(dir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	oldDir, newDir := t.TempDir(), t.TempDir()
+	write(oldDir, map[string]string{
+		"app.star":    "load(\"render.star\", \"render\")\n",
+		"old.star":    "x = 1\n",
+		"icon.png":    "\x89PNG\x00\x01",
+		"app.json":    `{"version": "a"}`,
+		"config.json": `{"who": "me"}`,
+	})
+	write(newDir, map[string]string{
+		"app.star":      "load(\"render.star\", \"render\")\n",
+		"lib/util.star": "def f():\n    pass\n",
+		"icon.png":      "\x89PNG\x00\x02",
+		"app.json":      `{"version": "b"}`,
+		"config.json":   `{"who": "you"}`,
+	})
+
+	// Unchanged files and Mosaic's own app.json and config.json are left out
+	want := `Binary files installed/icon.png and latest/icon.png differ
+--- /dev/null
++++ latest/lib/util.star
+@@ -0,0 +1,2 @@
++def f():
++    pass
+--- installed/old.star
++++ /dev/null
+@@ -1,1 +0,0 @@
+-x = 1
+`
+	if got := diffAppDirs(oldDir, newDir, "installed", "latest"); got != want {
+		t.Errorf("diff =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,56 @@
+package apps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// manifestFile is the name of a community app's manifest
+const manifestFile = "manifest.yaml"
+
+// Manifest is the manifest.yaml that community apps ship with
+type Manifest struct {
+	ID                  string `yaml:"id"`
+	Name                string `yaml:"name"`
+	Summary             string `yaml:"summary"`
+	Desc                string `yaml:"desc"`
+	Author              string `yaml:"author"`
+	Category            string `yaml:"category"`
+	FileName            string `yaml:"fileName"`
+	PackageName         string `yaml:"packageName"`
+	RecommendedInterval int    `yaml:"recommendedInterval"` // minutes
+}
+
+// readManifest reads the manifest of an app directory. It returns nil and no
+// error if the app has none.
+func readManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", manifestFile, err)
+	}
+	return &m, nil
+}
+
+// apply copies the manifest's metadata to an app, keeping what the manifest
+// leaves out. The app keeps its ID, which is its directory name.
+func (m *Manifest) apply(app *App) {
+	app.Name = orElse(m.Name, app.Name)
+	app.Summary = orElse(m.Summary, app.Summary)
+	app.Description = orElse(m.Desc, app.Description)
+	app.Author = orElse(m.Author, app.Author)
+	app.Category = orElse(m.Category, app.Category)
+	if m.RecommendedInterval > 0 {
+		app.RecommendedInterval = m.RecommendedInterval
+	}
+}
@@ -1,11 +1,10 @@
 package apps
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -28,6 +27,16 @@ type App struct {
 	SchemaJSON  []byte            `json:"schema_json,omitempty" yaml:"-"`
 	Source      string            `json:"source" yaml:"source"` // "local", "community", "custom"
 	Installed   time.Time         `json:"installed" yaml:"installed"`
+
+	// Suggested refresh interval in minutes, from the app's manifest
+	RecommendedInterval int `json:"recommended_interval,omitempty" yaml:"recommended_interval,omitempty"`
+
+	// Community apps: the upstream version installed (tree SHA of the app's
+	// directory) and the latest one seen upstream
+	Version         string    `json:"version,omitempty" yaml:"version,omitempty"`
+	LatestVersion   string    `json:"latest_version,omitempty" yaml:"latest_version,omitempty"`
+	UpdateAvailable bool      `json:"update_available" yaml:"update_available"`
+	Updated         time.Time `json:"updated,omitempty" yaml:"updated,omitempty"`
 }
 
 // CommunityApp represents an app from the community index
@@ -55,6 +64,10 @@ type Repository struct {
 	appsDir        string
 	communityIndex *CommunityIndex
 	installed      map[string]*App
+	source         Source // where community apps come from
+
+	// Serializes updates and rollbacks, which move app directories around
+	updateMu sync.Mutex
 }
 
 // NewRepository creates a new app repository
@@ -70,6 +83,7 @@ func NewRepository(dataDir string) (*Repository, error) {
 		dataDir:   dataDir,
 		appsDir:   appsDir,
 		installed: make(map[string]*App),
+		source:    DefaultSource,
 	}
 
 	// Load community index
@@ -85,23 +99,35 @@ func NewRepository(dataDir string) (*Repository, error) {
 	return r, nil
 }
 
-// List returns all installed apps
+// List returns copies of all installed apps
 func (r *Repository) List() []*App {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	apps := make([]*App, 0, len(r.installed))
 	for _, app := range r.installed {
-		apps = append(apps, app)
+		apps = append(apps, app.clone())
 	}
 	return apps
 }
 
-// Get returns an installed app by ID
+// Get returns a copy of an installed app by ID. Updates change the installed
+// app under the repository's lock, so callers never see it change.
 func (r *Repository) Get(id string) *App {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	return r.installed[id]
+
+	if app, ok := r.installed[id]; ok {
+		return app.clone()
+	}
+	return nil
+}
+
+// clone returns a copy of an app. Its config and schema are shared: they are
+// replaced, never changed in place.
+func (a *App) clone() *App {
+	c := *a
+	return &c
 }
 
 // GetPath returns the path to an app's .star file
@@ -115,8 +141,9 @@ func (r *Repository) GetPath(id string) string {
 	return ""
 }
 
-// Install downloads and installs an app from the community
-func (r *Repository) Install(id string) (*App, error) {
+// Install downloads and installs an app from the community. The download is
+// abandoned when ctx is done.
+func (r *Repository) Install(ctx context.Context, id string) (*App, error) {
 	// Check if already installed
 	if existing := r.Get(id); existing != nil {
 		return existing, nil
@@ -128,55 +155,9 @@ func (r *Repository) Install(id string) (*App, error) {
 		return nil, fmt.Errorf("app %q not found in community index", id)
 	}
 
-	// First, get the directory listing to find the .star file
-	listURL := fmt.Sprintf("https://api.github.com/repos/tidbyt/community/contents/apps/%s", id)
-	listResp, err := http.Get(listURL)
-	if err != nil {
-		return nil, fmt.Errorf("listing app directory: %w", err)
-	}
-	defer listResp.Body.Close()
-
-	if listResp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("app directory not found: %s", listResp.Status)
-	}
-
-	var files []struct {
-		Name string `json:"name"`
-	}
-	if err := json.NewDecoder(listResp.Body).Decode(&files); err != nil {
-		return nil, fmt.Errorf("parsing directory listing: %w", err)
-	}
-
-	// Find the .star file
-	var starFile string
-	for _, f := range files {
-		if strings.HasSuffix(f.Name, ".star") {
-			starFile = f.Name
-			break
-		}
-	}
-	if starFile == "" {
-		return nil, fmt.Errorf("no .star file found in app %s", id)
-	}
-
-	// Download the .star file
-	url := fmt.Sprintf("https://raw.githubusercontent.com/tidbyt/community/main/apps/%s/%s", id, starFile)
-	log.Printf("Downloading app from: %s", url)
-	
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("downloading app: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("download failed: %s", resp.Status)
-	}
-
-	source, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("reading app source: %w", err)
-	}
+	r.mu.RLock()
+	src := r.source
+	r.mu.RUnlock()
 
 	// Create app directory
 	appDir := filepath.Join(r.appsDir, id)
@@ -184,10 +165,16 @@ func (r *Repository) Install(id string) (*App, error) {
 		return nil, fmt.Errorf("creating app directory: %w", err)
 	}
 
-	// Write star file
-	starPath := filepath.Join(appDir, id+".star")
-	if err := os.WriteFile(starPath, source, 0644); err != nil {
-		return nil, fmt.Errorf("writing app file: %w", err)
+	starPath, err := downloadApp(ctx, sourceClient, src, id, appDir)
+	if err != nil {
+		os.RemoveAll(appDir)
+		return nil, err
+	}
+
+	// Remember the version, to tell when the app changes upstream
+	version, err := fetchAppVersion(ctx, sourceClient, src, id)
+	if err != nil {
+		log.Printf("Warning: could not get version of %s: %v", id, err)
 	}
 
 	// Extract schema from app
@@ -209,6 +196,14 @@ func (r *Repository) Install(id string) (*App, error) {
 		SchemaJSON: schemaJSON,
 		Source:     "community",
 		Installed:  time.Now(),
+		Version:    version,
+	}
+
+	// The app's own manifest is more complete than the index
+	if manifest, err := readManifest(appDir); err != nil {
+		log.Printf("Warning: could not read manifest for %s: %v", id, err)
+	} else if manifest != nil {
+		manifest.apply(app)
 	}
 
 	// Write metadata
@@ -217,7 +212,7 @@ func (r *Repository) Install(id string) (*App, error) {
 	os.WriteFile(metaPath, metaData, 0644)
 
 	r.mu.Lock()
-	r.installed[id] = app
+	r.installed[id] = app.clone()
 	r.mu.Unlock()
 
 	log.Printf("Installed community app: %s", id)
@@ -263,7 +258,7 @@ func (r *Repository) InstallFromSource(id, name string, source []byte) (*App, er
 
 	// Add to installed
 	r.mu.Lock()
-	r.installed[id] = app
+	r.installed[id] = app.clone()
 	r.mu.Unlock()
 
 	log.Printf("Installed app: %s", id)
@@ -285,13 +280,16 @@ func (r *Repository) Uninstall(id string) error {
 	if err := os.RemoveAll(appDir); err != nil {
 		return fmt.Errorf("removing app directory: %w", err)
 	}
+	os.RemoveAll(r.previousVersionDir(id))
 
 	delete(r.installed, id)
 	log.Printf("Uninstalled app: %s", id)
 	return nil
 }
 
-// SaveConfig saves config for an app
+// SaveConfig checks config against the app's schema and saves it with the
+// schema's defaults filled in. A config that doesn't fit the schema is
+// rejected with ConfigErrors.
 func (r *Repository) SaveConfig(id string, config map[string]string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -301,6 +299,10 @@ func (r *Repository) SaveConfig(id string, config map[string]string) error {
 		return fmt.Errorf("app %q not installed", id)
 	}
 
+	config, err := ValidateConfig(app.SchemaJSON, config)
+	if err != nil {
+		return err
+	}
 	app.Config = config
 
 	// Write config file
@@ -369,26 +371,31 @@ func (r *Repository) SearchCommunity(query string) []CommunityApp {
 }
 
 func (r *Repository) loadCommunityIndex() error {
-	// Try bundled index first (in /app/data, not /data which is a volume mount)
+	// The bundled index and one refreshed or provided by the user; the newer
+	// one wins
 	paths := []string{
-		"/app/data/community-apps.json",  // Bundled in Docker image
-		filepath.Join(r.dataDir, "community-apps.json"),  // User-provided
+		bundledIndexPath,                             // Bundled in Docker image
+		filepath.Join(r.dataDir, communityIndexFile), // Refreshed or user-provided
 	}
-	
-	var data []byte
-	var err error
+
 	var loadedPath string
-	
-	for _, path := range paths {
-		log.Printf("Trying community index: %s", path)
-		data, err = os.ReadFile(path)
-		if err == nil {
-			loadedPath = path
-			break
+	var lastErr error
+	for _, indexPath := range paths {
+		log.Printf("Trying community index: %s", indexPath)
+		index, err := readCommunityIndex(indexPath)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				lastErr = err
+			}
+			continue
+		}
+		if r.communityIndex == nil || index.Updated.After(r.communityIndex.Updated) {
+			r.communityIndex = index
+			loadedPath = indexPath
 		}
 	}
-	
-	if err != nil {
+
+	if r.communityIndex == nil {
 		log.Printf("Failed to read community index from any location")
 		// Generate minimal index for testing
 		r.communityIndex = &CommunityIndex{
@@ -397,17 +404,10 @@ func (r *Repository) loadCommunityIndex() error {
 			Apps:    []CommunityApp{},
 		}
 		log.Println("No community index found, starting with empty index")
-		return nil
-	}
-	log.Printf("Read %d bytes from community index at %s", len(data), loadedPath)
-
-	var index CommunityIndex
-	if err := json.Unmarshal(data, &index); err != nil {
-		return fmt.Errorf("parsing community index: %w", err)
+		return lastErr
 	}
 
-	r.communityIndex = &index
-	log.Printf("Loaded community index: %d apps", index.Count)
+	log.Printf("Loaded community index from %s: %d apps", loadedPath, r.communityIndex.Count)
 	return nil
 }
 
@@ -426,25 +426,15 @@ func (r *Repository) discoverApps() error {
 		}
 
 		appID := entry.Name()
-		appDir := filepath.Join(r.appsDir, appID)
-
-		// Look for .star file
-		starPath := filepath.Join(appDir, appID+".star")
-		if _, err := os.Stat(starPath); os.IsNotExist(err) {
-			// Try any .star file
-			files, _ := filepath.Glob(filepath.Join(appDir, "*.star"))
-			if len(files) > 0 {
-				starPath = files[0]
-			} else {
-				continue
-			}
+		if strings.HasPrefix(appID, ".") {
+			continue
 		}
+		appDir := filepath.Join(r.appsDir, appID)
 
 		// Load metadata if available
 		app := &App{
 			ID:     appID,
 			Name:   appID,
-			Path:   starPath,
 			Source: "local",
 		}
 
@@ -453,6 +443,22 @@ func (r *Repository) discoverApps() error {
 			json.Unmarshal(data, app)
 		}
 
+		manifest, err := readManifest(appDir)
+		if err != nil {
+			log.Printf("Warning: could not read manifest for %s: %v", appID, err)
+		}
+
+		// Look for the main .star file
+		candidates := []string{filepath.Base(app.Path)}
+		if manifest != nil {
+			candidates = append(candidates, manifest.FileName)
+		}
+		starPath := findMainStar(appDir, appID, candidates...)
+		if starPath == "" {
+			continue
+		}
+		app.Path = starPath
+
 		// Load config if available
 		configPath := filepath.Join(appDir, "config.json")
 		if data, err := os.ReadFile(configPath); err == nil {
@@ -462,8 +468,10 @@ func (r *Repository) discoverApps() error {
 			}
 		}
 
-		// Parse app header for metadata
-		if source, err := os.ReadFile(starPath); err == nil {
+		// Metadata from the manifest, else from the app header
+		if manifest != nil {
+			manifest.apply(app)
+		} else if source, err := os.ReadFile(starPath); err == nil {
 			r.parseAppHeader(app, source)
 		}
 
@@ -483,6 +491,29 @@ func (r *Repository) discoverApps() error {
 	return nil
 }
 
+// findMainStar returns the main .star file of an app directory: the first of
+// names that exists, else <id>.star, else the one named most like the app.
+// It returns "" if the directory has no .star file.
+func findMainStar(appDir, appID string, names ...string) string {
+	for _, name := range append(names, appID+".star") {
+		if !strings.HasSuffix(name, ".star") {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(appDir, name)); err == nil && !info.IsDir() {
+			return filepath.Join(appDir, name)
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(appDir, "*.star"))
+	if len(files) == 0 {
+		return ""
+	}
+	for i, f := range files {
+		files[i] = filepath.Base(f)
+	}
+	return filepath.Join(appDir, mainStarFile(appID, files))
+}
+
 func (r *Repository) parseAppHeader(app *App, source []byte) {
 	lines := strings.Split(string(source), "\n")
 	
@@ -0,0 +1,183 @@
+package apps
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Config error codes
+const (
+	ConfigUnknownField = "unknown_field"
+	ConfigInvalidValue = "invalid_value"
+	ConfigRequired     = "required"
+)
+
+// ConfigError is a config value that doesn't fit an app's schema
+type ConfigError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ConfigErrors is returned when a config doesn't fit an app's schema
+type ConfigErrors []ConfigError
+
+func (e ConfigErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Field + ": " + err.Message
+	}
+	return "invalid config: " + strings.Join(msgs, "; ")
+}
+
+// schemaField is a field of a Pixlet schema
+type schemaField struct {
+	ID       string         `json:"id"`
+	Type     string         `json:"type"`
+	Name     string         `json:"name"`
+	Default  string         `json:"default"`
+	Required bool           `json:"required"`
+	Options  []schemaOption `json:"options"`
+}
+
+type schemaOption struct {
+	Display string `json:"display"`
+	Value   string `json:"value"`
+}
+
+var colorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+// ValidateConfig checks a config against an app's schema and returns it with
+// the schema's defaults filled in. Configs of apps without a schema are
+// returned as they are. Apps with generated fields can have fields the schema
+// doesn't list, so their unknown keys are allowed.
+func ValidateConfig(schemaJSON []byte, config map[string]string) (map[string]string, error) {
+	if len(schemaJSON) == 0 {
+		return config, nil
+	}
+
+	var schema struct {
+		Schema []schemaField `json:"schema"`
+	}
+	if err := json.Unmarshal(schemaJSON, &schema); err != nil {
+		return nil, fmt.Errorf("parsing schema: %w", err)
+	}
+
+	fields := make(map[string]schemaField, len(schema.Schema))
+	generated := false
+	for _, f := range schema.Schema {
+		fields[f.ID] = f
+		if f.Type == "generated" {
+			generated = true
+		}
+	}
+
+	out := make(map[string]string, len(config))
+	var errs ConfigErrors
+	for key, value := range config {
+		field, ok := fields[key]
+		if !ok && !generated {
+			errs = append(errs, ConfigError{key, ConfigUnknownField, "not a field of this app"})
+			continue
+		}
+		if value != "" {
+			if msg := checkValue(field, value); msg != "" {
+				errs = append(errs, ConfigError{key, ConfigInvalidValue, msg})
+				continue
+			}
+		}
+		out[key] = value
+	}
+
+	for _, field := range schema.Schema {
+		if _, ok := config[field.ID]; !ok && field.Default != "" {
+			out[field.ID] = field.Default
+		}
+		if field.Required && out[field.ID] == "" && !hasError(errs, field.ID) {
+			errs = append(errs, ConfigError{field.ID, ConfigRequired, "is required"})
+		}
+	}
+
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+		return nil, errs
+	}
+	return out, nil
+}
+
+// checkValue checks a value against the type of its field and returns what's
+// wrong with it, or "" if it fits
+func checkValue(field schemaField, value string) string {
+	switch field.Type {
+	case "onoff", "toggle":
+		if value != "true" && value != "false" {
+			return `must be "true" or "false"`
+		}
+	case "color":
+		if !colorPattern.MatchString(value) {
+			return "must be a hex color like #ff8800"
+		}
+	case "dropdown", "select":
+		if len(field.Options) == 0 {
+			return ""
+		}
+		values := make([]string, len(field.Options))
+		for i, opt := range field.Options {
+			if opt.Value == value {
+				return ""
+			}
+			values[i] = strconv.Quote(opt.Value)
+		}
+		return "must be one of " + strings.Join(values, ", ")
+	case "location":
+		return checkLocation(value)
+	}
+	return ""
+}
+
+// checkLocation checks a location field: a JSON object with lat and lng,
+// as numbers or strings, and optionally timezone
+func checkLocation(value string) string {
+	var loc struct {
+		Lat      json.RawMessage `json:"lat"`
+		Lng      json.RawMessage `json:"lng"`
+		Timezone *string         `json:"timezone"`
+	}
+	if err := json.Unmarshal([]byte(value), &loc); err != nil {
+		return "must be a JSON object with lat, lng and timezone"
+	}
+
+	lat, ok := jsonNumber(loc.Lat)
+	if !ok || lat < -90 || lat > 90 {
+		return "lat must be a number between -90 and 90"
+	}
+	lng, ok := jsonNumber(loc.Lng)
+	if !ok || lng < -180 || lng > 180 {
+		return "lng must be a number between -180 and 180"
+	}
+	return ""
+}
+
+// jsonNumber reads a JSON number, or a string holding one
+func jsonNumber(raw json.RawMessage) (float64, bool) {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		n, err := strconv.ParseFloat(s, 64)
+		return n, err == nil
+	}
+	var n float64
+	return n, json.Unmarshal(raw, &n) == nil
+}
+
+func hasError(errs ConfigErrors, field string) bool {
+	for _, err := range errs {
+		if err.Field == field {
+			return true
+		}
+	}
+	return false
+}
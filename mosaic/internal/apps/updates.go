@@ -0,0 +1,295 @@
+package apps
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/johnfernkas/mosaic-addon/internal/pixlet"
+)
+
+// AppUpdate describes a change of an app's version
+type AppUpdate struct {
+	AppID       string `json:"app_id"`
+	FromVersion string `json:"from_version,omitempty"`
+	ToVersion   string `json:"to_version,omitempty"`
+	Diff        string `json:"diff"` // unified diff of the app's files
+}
+
+// CheckUpdates compares the installed community apps with the source and
+// marks those that changed upstream. It returns the IDs of apps with an
+// update available.
+func (r *Repository) CheckUpdates(ctx context.Context) ([]string, error) {
+	r.mu.RLock()
+	src := r.source
+	r.mu.RUnlock()
+
+	versions, err := fetchAppVersions(ctx, sourceClient, src)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var updates []string
+	for id, app := range r.installed {
+		latest, ok := versions[id]
+		if app.Source != "community" || !ok {
+			continue
+		}
+		app.LatestVersion = latest
+		// Apps installed before versions were recorded can't be compared
+		app.UpdateAvailable = app.Version != "" && app.Version != latest
+		if app.UpdateAvailable {
+			updates = append(updates, id)
+		}
+	}
+	return updates, nil
+}
+
+// PreviewUpdate downloads the latest version of a community app and returns
+// how it differs from the installed one, without installing it
+func (r *Repository) PreviewUpdate(ctx context.Context, id string) (*AppUpdate, error) {
+	app := r.Get(id)
+	if app == nil {
+		return nil, fmt.Errorf("app %q not installed", id)
+	}
+
+	stage, _, update, err := r.stageUpdate(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	os.RemoveAll(stage)
+	return update, nil
+}
+
+// Update installs the latest version of a community app, keeping its
+// config. The installed version is kept so the update can be rolled back.
+func (r *Repository) Update(ctx context.Context, id string) (*AppUpdate, error) {
+	r.updateMu.Lock()
+	defer r.updateMu.Unlock()
+
+	app := r.Get(id)
+	if app == nil {
+		return nil, fmt.Errorf("app %q not installed", id)
+	}
+
+	stage, mainFile, update, err := r.stageUpdate(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(stage)
+
+	// Keep the user's config with the new version
+	appDir := filepath.Dir(app.Path)
+	if err := copyIfExists(filepath.Join(appDir, "config.json"), filepath.Join(stage, "config.json")); err != nil {
+		return nil, fmt.Errorf("keeping config: %w", err)
+	}
+
+	// Move the installed version aside and the new one in
+	backup := r.previousVersionDir(id)
+	if err := os.RemoveAll(backup); err != nil {
+		return nil, fmt.Errorf("removing old backup: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(backup), 0755); err != nil {
+		return nil, fmt.Errorf("creating backup directory: %w", err)
+	}
+	if err := os.Rename(appDir, backup); err != nil {
+		return nil, fmt.Errorf("backing up app: %w", err)
+	}
+	if err := os.Rename(stage, appDir); err != nil {
+		os.Rename(backup, appDir)
+		return nil, fmt.Errorf("installing update: %w", err)
+	}
+
+	r.refreshInstalled(id, func(app *App) {
+		app.Path = filepath.Join(appDir, mainFile)
+		app.Version = update.ToVersion
+		app.LatestVersion = update.ToVersion
+		app.UpdateAvailable = false
+		app.Updated = time.Now()
+	})
+
+	log.Printf("Updated app %s to %s", id, update.ToVersion)
+	return update, nil
+}
+
+// Rollback swaps an app back to the version it had before its last update,
+// keeping its current config. Rolling back again returns to the update.
+func (r *Repository) Rollback(id string) (*AppUpdate, error) {
+	r.updateMu.Lock()
+	defer r.updateMu.Unlock()
+
+	app := r.Get(id)
+	if app == nil {
+		return nil, fmt.Errorf("app %q not installed", id)
+	}
+
+	backup := r.previousVersionDir(id)
+	previous, err := readAppMeta(backup)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("app %q has no previous version", id)
+		}
+		return nil, fmt.Errorf("reading previous version: %w", err)
+	}
+
+	appDir := filepath.Dir(app.Path)
+	update := &AppUpdate{
+		AppID:       id,
+		FromVersion: app.Version,
+		ToVersion:   previous.Version,
+		Diff:        diffAppDirs(appDir, backup, "installed", "previous"),
+	}
+
+	if err := copyIfExists(filepath.Join(appDir, "config.json"), filepath.Join(backup, "config.json")); err != nil {
+		return nil, fmt.Errorf("keeping config: %w", err)
+	}
+
+	// Swap the two versions
+	swap := filepath.Join(r.dataDir, ".rollback-"+id)
+	if err := os.RemoveAll(swap); err != nil {
+		return nil, fmt.Errorf("clearing rollback directory: %w", err)
+	}
+	if err := os.Rename(appDir, swap); err != nil {
+		return nil, fmt.Errorf("moving installed version: %w", err)
+	}
+	if err := os.Rename(backup, appDir); err != nil {
+		os.Rename(swap, appDir)
+		return nil, fmt.Errorf("restoring previous version: %w", err)
+	}
+	if err := os.Rename(swap, backup); err != nil {
+		log.Printf("Warning: could not keep %s for rolling forward: %v", id, err)
+		os.RemoveAll(swap)
+	}
+
+	mainFile := findMainStar(appDir, id, filepath.Base(previous.Path))
+	r.refreshInstalled(id, func(app *App) {
+		app.Path = mainFile
+		app.Version = previous.Version
+		app.UpdateAvailable = app.LatestVersion != "" && app.Version != app.LatestVersion
+		app.Updated = time.Now()
+	})
+
+	log.Printf("Rolled back app %s to %s", id, previous.Version)
+	return update, nil
+}
+
+// HasPreviousVersion reports whether an app can be rolled back
+func (r *Repository) HasPreviousVersion(id string) bool {
+	_, err := os.Stat(r.previousVersionDir(id))
+	return err == nil
+}
+
+// stageUpdate downloads the latest version of a community app into a
+// directory next to the apps dir and diffs it against the installed one. It
+// returns the directory and the name of the new version's main .star file.
+func (r *Repository) stageUpdate(ctx context.Context, app *App) (string, string, *AppUpdate, error) {
+	if app.Source != "community" {
+		return "", "", nil, fmt.Errorf("app %q is not a community app", app.ID)
+	}
+
+	r.mu.RLock()
+	src := r.source
+	r.mu.RUnlock()
+
+	latest, err := fetchAppVersion(ctx, sourceClient, src, app.ID)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if latest == "" {
+		return "", "", nil, fmt.Errorf("app %q no longer exists upstream", app.ID)
+	}
+
+	stage, err := os.MkdirTemp(r.dataDir, ".update-"+app.ID+"-")
+	if err != nil {
+		return "", "", nil, fmt.Errorf("creating staging directory: %w", err)
+	}
+	starPath, err := downloadApp(ctx, sourceClient, src, app.ID, stage)
+	if err != nil {
+		os.RemoveAll(stage)
+		return "", "", nil, err
+	}
+
+	return stage, filepath.Base(starPath), &AppUpdate{
+		AppID:       app.ID,
+		FromVersion: app.Version,
+		ToVersion:   latest,
+		Diff:        diffAppDirs(filepath.Dir(app.Path), stage, "installed", "latest"),
+	}, nil
+}
+
+// refreshInstalled applies change to an installed app after its source
+// changed, re-reads its schema and manifest and saves its metadata. The
+// installed app is only changed under the lock.
+func (r *Repository) refreshInstalled(id string, change func(app *App)) {
+	app := r.Get(id)
+	if app == nil {
+		return
+	}
+	change(app)
+
+	renderer := pixlet.NewRenderer(64, 32)
+	schemaJSON, err := renderer.GetSchema(app.Path)
+	if err != nil {
+		log.Printf("Warning: could not extract schema for %s: %v", id, err)
+	}
+	manifest, err := readManifest(filepath.Dir(app.Path))
+	if err != nil {
+		log.Printf("Warning: could not read manifest for %s: %v", id, err)
+	}
+
+	r.mu.Lock()
+	installed, ok := r.installed[id]
+	if !ok {
+		r.mu.Unlock()
+		return
+	}
+	change(installed)
+	installed.SchemaJSON = schemaJSON
+	if manifest != nil {
+		manifest.apply(installed)
+	}
+	metaData, _ := json.MarshalIndent(installed, "", "  ")
+	r.mu.Unlock()
+
+	os.WriteFile(filepath.Join(filepath.Dir(app.Path), "app.json"), metaData, 0644)
+}
+
+// previousVersionDir is where the version an app had before its last update
+// is kept
+func (r *Repository) previousVersionDir(id string) string {
+	return filepath.Join(r.dataDir, "app-versions", id)
+}
+
+// readAppMeta reads the app.json of an app directory
+func readAppMeta(dir string) (*App, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "app.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var app App
+	if err := json.Unmarshal(data, &app); err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// copyIfExists copies a file, doing nothing if it doesn't exist
+func copyIfExists(from, to string) error {
+	data, err := os.ReadFile(from)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(to, data, 0644)
+}
@@ -0,0 +1,195 @@
+package brightness
+
+import (
+	"testing"
+	"time"
+)
+
+var (
+	newYorkSummer = time.FixedZone("EDT", -4*3600)
+	newYorkWinter = time.FixedZone("EST", -5*3600)
+	sydneySummer  = time.FixedZone("AEDT", 11*3600)
+)
+
+// near reports whether t is within two minutes of hh:mm on its date
+func near(t time.Time, hour, minute int) bool {
+	y, m, d := t.Date()
+	want := time.Date(y, m, d, hour, minute, 0, 0, t.Location())
+	diff := t.Sub(want)
+	return diff > -2*time.Minute && diff < 2*time.Minute
+}
+
+func TestSunTimes(t *testing.T) {
+	// Published NOAA sunrise and sunset times
+	tests := []struct {
+		place        string
+		day          time.Time
+		lat, lng     float64
+		riseH, riseM int
+		setH, setM   int
+	}{
+		{"New York, summer solstice", time.Date(2024, 6, 20, 0, 0, 0, 0, newYorkSummer), 40.7128, -74.0060, 5, 25, 20, 31},
+		{"New York, winter solstice", time.Date(2024, 12, 21, 0, 0, 0, 0, newYorkWinter), 40.7128, -74.0060, 7, 17, 16, 32},
+		{"London, equinox", time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC), 51.5074, -0.1278, 6, 3, 18, 15},
+		{"Sydney, new year", time.Date(2024, 1, 1, 0, 0, 0, 0, sydneySummer), -33.8688, 151.2093, 5, 47, 20, 9},
+	}
+	for _, tt := range tests {
+		rise, set, ok := SunTimes(tt.day, tt.lat, tt.lng)
+		if !ok {
+			t.Errorf("%s: no sunrise or sunset", tt.place)
+			continue
+		}
+		if !near(rise, tt.riseH, tt.riseM) || rise.Location() != tt.day.Location() {
+			t.Errorf("%s: sunrise = %s, want about %02d:%02d", tt.place, rise.Format("2006-01-02 15:04 MST"), tt.riseH, tt.riseM)
+		}
+		if !near(set, tt.setH, tt.setM) || set.Location() != tt.day.Location() {
+			t.Errorf("%s: sunset = %s, want about %02d:%02d", tt.place, set.Format("2006-01-02 15:04 MST"), tt.setH, tt.setM)
+		}
+	}
+}
+
+func TestSunTimesPolar(t *testing.T) {
+	// Tromsø has midnight sun in June and polar night in December
+	for _, day := range []time.Time{
+		time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 12, 21, 0, 0, 0, 0, time.UTC),
+	} {
+		if rise, set, ok := SunTimes(day, 69.6492, 18.9553); ok {
+			t.Errorf("%s: sunrise %s and sunset %s, want none", day.Format("2006-01-02"), rise, set)
+		}
+	}
+}
+
+func TestLevel(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 6, 20, hour, minute, 0, 0, time.UTC)
+	}
+	schedule := &Schedule{Points: []Point{
+		{Time: "07:00", Brightness: 80, RampMins: 60},
+		{Time: "22:00", Brightness: 10},
+	}}
+
+	tests := []struct {
+		t         time.Time
+		wantLevel int
+		wantNext  time.Time
+	}{
+		{at(3, 0), 10, at(7, 0)}, // yesterday's 22:00 still holds
+		{at(7, 0), 10, at(22, 0)},
+		{at(7, 30), 45, at(22, 0)}, // halfway up the ramp
+		{at(8, 0), 80, at(22, 0)},
+		{at(21, 59), 80, at(22, 0)},
+		{at(22, 0), 10, at(7, 0).AddDate(0, 0, 1)},
+		{at(23, 59), 10, at(7, 0).AddDate(0, 0, 1)},
+	}
+	for _, tt := range tests {
+		level, next := schedule.Level(tt.t)
+		if level != tt.wantLevel || !next.Equal(tt.wantNext) {
+			t.Errorf("Level(%s) = %d, %s; want %d, %s", tt.t.Format("15:04"), level, next.Format("01-02 15:04"), tt.wantLevel, tt.wantNext.Format("01-02 15:04"))
+		}
+	}
+}
+
+func TestLevelRampFromRamp(t *testing.T) {
+	// The second ramp starts where the first had got to
+	schedule := &Schedule{Points: []Point{
+		{Time: "06:00", Brightness: 100},
+		{Time: "20:00", Brightness: 0, RampMins: 120},
+		{Time: "21:00", Brightness: 100, RampMins: 60},
+	}}
+	tests := []struct {
+		hour, minute int
+		want         int
+	}{
+		{20, 30, 75},
+		{21, 0, 50},
+		{21, 30, 75},
+		{22, 0, 100},
+	}
+	for _, tt := range tests {
+		level, _ := schedule.Level(time.Date(2024, 6, 20, tt.hour, tt.minute, 0, 0, time.UTC))
+		if level != tt.want {
+			t.Errorf("Level(%02d:%02d) = %d, want %d", tt.hour, tt.minute, level, tt.want)
+		}
+	}
+}
+
+func TestLevelSun(t *testing.T) {
+	// New York, sunset about 20:31 on the solstice
+	schedule := &Schedule{
+		Latitude:  40.7128,
+		Longitude: -74.0060,
+		Points: []Point{
+			{Sun: "sunrise", Brightness: 80},
+			{Sun: "sunset", Offset: 30, Brightness: 20},
+		},
+	}
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 6, 20, hour, minute, 0, 0, newYorkSummer)
+	}
+
+	level, next := schedule.Level(at(12, 0))
+	if level != 80 || !near(next, 21, 1) {
+		t.Errorf("Level(12:00) = %d, %s; want 80, about 21:01", level, next.Format("15:04"))
+	}
+	if level, _ := schedule.Level(at(20, 55)); level != 80 {
+		t.Errorf("Level(20:55) = %d, want 80", level)
+	}
+	if level, _ := schedule.Level(at(21, 10)); level != 20 {
+		t.Errorf("Level(21:10) = %d, want 20", level)
+	}
+	if level, _ := schedule.Level(at(4, 0)); level != 20 {
+		t.Errorf("Level(04:00) = %d, want 20", level)
+	}
+}
+
+func TestLevelPolar(t *testing.T) {
+	// Sun points are skipped on days the sun doesn't set; fixed points still
+	// apply
+	schedule := &Schedule{
+		Latitude:  69.6492,
+		Longitude: 18.9553,
+		Points: []Point{
+			{Sun: "sunset", Brightness: 20},
+			{Time: "08:00", Brightness: 90},
+		},
+	}
+	day := time.Date(2024, 6, 21, 23, 0, 0, 0, time.UTC)
+	if level, _ := schedule.Level(day); level != 90 {
+		t.Errorf("Level = %d, want 90", level)
+	}
+
+	// With nothing left, full brightness and no next change
+	schedule.Points = schedule.Points[:1]
+	if level, next := schedule.Level(day); level != 100 || !next.IsZero() {
+		t.Errorf("Level = %d, %s; want 100 and no next change", level, next)
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	valid := []Schedule{
+		{Points: []Point{{Time: "07:00", Brightness: 80}}},
+		{Latitude: 40.7, Longitude: -74, Points: []Point{{Sun: "sunset", Offset: -30, Brightness: 40, RampMins: 45}}},
+	}
+	for i, s := range valid {
+		if err := s.Validate(); err != nil {
+			t.Errorf("valid %d: %v", i, err)
+		}
+	}
+
+	invalid := []Schedule{
+		{},
+		{Points: []Point{{Time: "24:00", Brightness: 80}}},
+		{Points: []Point{{Time: "07:00", Brightness: 101}}},
+		{Points: []Point{{Time: "07:00", Brightness: 80, RampMins: -1}}},
+		{Points: []Point{{Sun: "noon", Brightness: 80}}},
+		{Points: []Point{{Sun: "sunrise", Brightness: 80}}}, // no location
+		{Latitude: 91, Points: []Point{{Time: "07:00", Brightness: 80}}},
+		{Latitude: 40.7, Longitude: -74, Points: []Point{{Sun: "sunrise", Offset: 721, Brightness: 80}}},
+	}
+	for i, s := range invalid {
+		if err := s.Validate(); err == nil {
+			t.Errorf("invalid %d: no error", i)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package brightness
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Point is a scheduled brightness change, at a fixed time of day or relative
+// to sunrise or sunset
+type Point struct {
+	// Time of day in "HH:MM" form; used when Sun is empty
+	Time string `json:"time,omitempty"`
+
+	// Sun is "sunrise" or "sunset"; Offset shifts it by minutes (negative = before)
+	Sun    string `json:"sun,omitempty"`
+	Offset int    `json:"offset_mins,omitempty"`
+
+	// Brightness (0-100) from this point on
+	Brightness int `json:"brightness"`
+
+	// RampMins fades from the previous level over this many minutes
+	// instead of switching at once
+	RampMins int `json:"ramp_mins,omitempty"`
+}
+
+// Schedule is a daily list of brightness changes. Latitude and longitude
+// are only needed for sun-relative points.
+type Schedule struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Points    []Point `json:"points"`
+}
+
+// event is a point resolved to a concrete time
+type event struct {
+	at    time.Time
+	point Point
+}
+
+// Validate checks every point in the schedule
+func (s *Schedule) Validate() error {
+	if len(s.Points) == 0 {
+		return fmt.Errorf("schedule needs at least one point")
+	}
+	if s.Latitude < -90 || s.Latitude > 90 || s.Longitude < -180 || s.Longitude > 180 {
+		return fmt.Errorf("latitude must be between -90 and 90 and longitude between -180 and 180")
+	}
+
+	needsLocation := false
+	for i, p := range s.Points {
+		switch p.Sun {
+		case "":
+			if _, err := parseClock(p.Time); err != nil {
+				return fmt.Errorf("point %d: %w", i+1, err)
+			}
+		case "sunrise", "sunset":
+			needsLocation = true
+			if p.Offset < -720 || p.Offset > 720 {
+				return fmt.Errorf("point %d: offset_mins must be between -720 and 720", i+1)
+			}
+		default:
+			return fmt.Errorf("point %d: sun must be sunrise or sunset", i+1)
+		}
+		if p.Brightness < 0 || p.Brightness > 100 {
+			return fmt.Errorf("point %d: brightness must be between 0 and 100", i+1)
+		}
+		if p.RampMins < 0 || p.RampMins > 720 {
+			return fmt.Errorf("point %d: ramp_mins must be between 0 and 720", i+1)
+		}
+	}
+
+	if needsLocation && s.Latitude == 0 && s.Longitude == 0 {
+		return fmt.Errorf("sunrise and sunset points need latitude and longitude")
+	}
+	return nil
+}
+
+// Level returns the scheduled brightness at t and when the next scheduled
+// change starts. Days are taken in t's location.
+func (s *Schedule) Level(t time.Time) (int, time.Time) {
+	// Yesterday's events give the level just after midnight, tomorrow's the
+	// next change late in the evening
+	var events []event
+	for day := -1; day <= 1; day++ {
+		events = append(events, s.events(t.AddDate(0, 0, day))...)
+	}
+	if len(events) == 0 {
+		return 100, time.Time{}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].at.Before(events[j].at) })
+
+	current := -1
+	for i, e := range events {
+		if e.at.After(t) {
+			break
+		}
+		current = i
+	}
+
+	var next time.Time
+	if current+1 < len(events) {
+		next = events[current+1].at
+	}
+	if current < 0 {
+		return events[0].point.Brightness, next
+	}
+
+	return levelAt(events, current, t), next
+}
+
+// levelAt returns the level set by events[i] at t. While it ramps, the
+// starting level is wherever the previous event had got to.
+func levelAt(events []event, i int, t time.Time) int {
+	e := events[i]
+	ramp := time.Duration(e.point.RampMins) * time.Minute
+	elapsed := t.Sub(e.at)
+	if i == 0 || elapsed >= ramp {
+		return e.point.Brightness
+	}
+
+	from := levelAt(events, i-1, e.at)
+	progress := float64(elapsed) / float64(ramp)
+	return from + int(math.Round(float64(e.point.Brightness-from)*progress))
+}
+
+// events resolves every point to a time on the date of day. Sun points are
+// skipped on days the sun doesn't rise or set.
+func (s *Schedule) events(day time.Time) []event {
+	y, m, d := day.Date()
+	loc := day.Location()
+
+	var rise, set time.Time
+	sunOK := false
+	for _, p := range s.Points {
+		if p.Sun != "" {
+			rise, set, sunOK = SunTimes(day, s.Latitude, s.Longitude)
+			break
+		}
+	}
+
+	events := make([]event, 0, len(s.Points))
+	for _, p := range s.Points {
+		var at time.Time
+		switch p.Sun {
+		case "":
+			mins, err := parseClock(p.Time)
+			if err != nil {
+				continue
+			}
+			at = time.Date(y, m, d, mins/60, mins%60, 0, 0, loc)
+		case "sunrise":
+			if !sunOK {
+				continue
+			}
+			at = rise.Add(time.Duration(p.Offset) * time.Minute)
+		case "sunset":
+			if !sunOK {
+				continue
+			}
+			at = set.Add(time.Duration(p.Offset) * time.Minute)
+		default:
+			continue
+		}
+		events = append(events, event{at: at, point: p})
+	}
+	return events
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(v string) (int, error) {
+	parts := strings.Split(v, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid time %q (use HH:MM)", v)
+	}
+	h, err1 := strconv.Atoi(parts[0])
+	m, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("invalid time %q (use HH:MM)", v)
+	}
+	return h*60 + m, nil
+}
@@ -0,0 +1,54 @@
+package brightness
+
+import (
+	"math"
+	"time"
+)
+
+// SunTimes returns sunrise and sunset on the date of day, in day's location.
+// It uses the NOAA sunrise equation, which is accurate to a minute or two
+// outside the polar regions. ok is false when the sun doesn't rise or set
+// that day.
+func SunTimes(day time.Time, latitude, longitude float64) (sunrise, sunset time.Time, ok bool) {
+	y, m, d := day.Date()
+
+	// Days since J2000.0 (2000-01-01 12:00 UTC) at noon on this date
+	noon := time.Date(y, m, d, 12, 0, 0, 0, time.UTC)
+	n := math.Round(float64(noon.Unix())/86400 + 2440587.5 - 2451545.0)
+
+	// Mean solar noon at this longitude
+	jStar := n - longitude/360
+
+	meanAnomaly := math.Mod(357.5291+0.98560028*jStar, 360)
+	mRad := radians(meanAnomaly)
+	center := 1.9148*math.Sin(mRad) + 0.0200*math.Sin(2*mRad) + 0.0003*math.Sin(3*mRad)
+	eclipticLong := math.Mod(meanAnomaly+center+180+102.9372, 360)
+	lRad := radians(eclipticLong)
+
+	transit := 2451545.0 + jStar + 0.0053*math.Sin(mRad) - 0.0069*math.Sin(2*lRad)
+
+	sinDecl := math.Sin(lRad) * math.Sin(radians(23.4397))
+	cosDecl := math.Cos(math.Asin(sinDecl))
+
+	// -0.833 degrees accounts for refraction and the sun's radius
+	latRad := radians(latitude)
+	cosHourAngle := (math.Sin(radians(-0.833)) - math.Sin(latRad)*sinDecl) / (math.Cos(latRad) * cosDecl)
+	if cosHourAngle < -1 || cosHourAngle > 1 {
+		return time.Time{}, time.Time{}, false
+	}
+	hourAngle := math.Acos(cosHourAngle) * 180 / math.Pi
+
+	loc := day.Location()
+	sunrise = julianToTime(transit - hourAngle/360).In(loc)
+	sunset = julianToTime(transit + hourAngle/360).In(loc)
+	return sunrise, sunset, true
+}
+
+func julianToTime(jd float64) time.Time {
+	secs := (jd - 2440587.5) * 86400
+	return time.Unix(0, int64(secs*float64(time.Second)))
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
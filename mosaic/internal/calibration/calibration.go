@@ -0,0 +1,137 @@
+package calibration
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Profile describes the color correction for one physical panel
+type Profile struct {
+	// Gamma applied to each channel (1.0 = linear, 2.2 is typical for HUB75)
+	Gamma float64 `json:"gamma"`
+
+	// ChannelOrder is the order the panel expects the channels in,
+	// e.g. "rgb", "rbg" or "grb"
+	ChannelOrder string `json:"channel_order"`
+
+	// Per-channel gains (0.0-2.0) for white balance
+	RedGain   float64 `json:"red_gain"`
+	GreenGain float64 `json:"green_gain"`
+	BlueGain  float64 `json:"blue_gain"`
+
+	// MinBrightness is the lowest value (0-255) a lit channel is driven at,
+	// so dim colors don't vanish on panels with a poor low end
+	MinBrightness int `json:"min_brightness"`
+}
+
+// Default returns a profile that leaves pixels unchanged
+func Default() Profile {
+	return Profile{
+		Gamma:        1.0,
+		ChannelOrder: "rgb",
+		RedGain:      1.0,
+		GreenGain:    1.0,
+		BlueGain:     1.0,
+	}
+}
+
+// Validate checks that all values are in range
+func (p Profile) Validate() error {
+	if p.Gamma < 0.1 || p.Gamma > 5.0 {
+		return fmt.Errorf("gamma must be between 0.1 and 5.0")
+	}
+	if _, err := channelIndexes(p.ChannelOrder); err != nil {
+		return err
+	}
+	for name, gain := range map[string]float64{"red_gain": p.RedGain, "green_gain": p.GreenGain, "blue_gain": p.BlueGain} {
+		if gain < 0 || gain > 2.0 {
+			return fmt.Errorf("%s must be between 0.0 and 2.0", name)
+		}
+	}
+	if p.MinBrightness < 0 || p.MinBrightness > 255 {
+		return fmt.Errorf("min_brightness must be between 0 and 255")
+	}
+	return nil
+}
+
+// IsIdentity reports whether the profile leaves pixels unchanged
+func (p Profile) IsIdentity() bool {
+	return p.Gamma == 1.0 && strings.ToLower(p.ChannelOrder) == "rgb" &&
+		p.RedGain == 1.0 && p.GreenGain == 1.0 && p.BlueGain == 1.0 &&
+		p.MinBrightness == 0
+}
+
+// Table is a precomputed lookup table for a profile
+type Table struct {
+	identity bool
+	lut      [3][256]byte
+	order    [3]int
+}
+
+// NewTable builds the lookup table for a profile
+func NewTable(p Profile) (*Table, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	order, _ := channelIndexes(p.ChannelOrder)
+	t := &Table{
+		identity: p.IsIdentity(),
+		order:    order,
+	}
+
+	gains := [3]float64{p.RedGain, p.GreenGain, p.BlueGain}
+	for c := 0; c < 3; c++ {
+		for v := 0; v < 256; v++ {
+			out := math.Pow(float64(v)/255, p.Gamma) * gains[c] * 255
+			out = math.Round(math.Min(out, 255))
+			if v > 0 && out < float64(p.MinBrightness) {
+				out = float64(p.MinBrightness)
+			}
+			t.lut[c][v] = byte(out)
+		}
+	}
+	return t, nil
+}
+
+// Apply returns a corrected copy of an RGB buffer. The input is never
+// modified; an identity table returns it as-is.
+func (t *Table) Apply(rgb []byte) []byte {
+	if t == nil || t.identity {
+		return rgb
+	}
+
+	out := make([]byte, len(rgb))
+	for i := 0; i+2 < len(rgb); i += 3 {
+		corrected := [3]byte{
+			t.lut[0][rgb[i]],
+			t.lut[1][rgb[i+1]],
+			t.lut[2][rgb[i+2]],
+		}
+		out[i] = corrected[t.order[0]]
+		out[i+1] = corrected[t.order[1]]
+		out[i+2] = corrected[t.order[2]]
+	}
+	return out
+}
+
+// channelIndexes maps an order like "grb" to source channel indexes
+func channelIndexes(order string) ([3]int, error) {
+	var idx [3]int
+	order = strings.ToLower(order)
+	if len(order) != 3 {
+		return idx, fmt.Errorf("channel_order must be a permutation of \"rgb\"")
+	}
+
+	seen := 0
+	for i, ch := range order {
+		pos := strings.IndexRune("rgb", ch)
+		if pos < 0 || seen&(1<<pos) != 0 {
+			return idx, fmt.Errorf("channel_order must be a permutation of \"rgb\"")
+		}
+		seen |= 1 << pos
+		idx[i] = pos
+	}
+	return idx, nil
+}
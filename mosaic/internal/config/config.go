@@ -3,10 +3,15 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
+	"github.com/johnfernkas/mosaic-addon/internal/brightness"
+	"github.com/johnfernkas/mosaic-addon/internal/calibration"
+	"github.com/johnfernkas/mosaic-addon/internal/output"
 	"github.com/johnfernkas/mosaic-addon/internal/rotation"
 )
 
@@ -26,12 +31,57 @@ type Config struct {
 	DefaultDwell  int `json:"default_dwell_ms"`
 	Brightness    int `json:"brightness"`
 
+	// Automatic brightness; nil = fixed Brightness
+	BrightnessSchedule *brightness.Schedule `json:"brightness_schedule,omitempty"`
+
+	// Rendering
+	RenderCacheTTL int `json:"render_cache_ttl_secs"` // for apps without max_age; 0 = no reuse
+	RenderWorkers  int `json:"render_workers"`        // max concurrent renders across displays
+
+	// What to do when an app fails to render, unless its rotation entry
+	// says otherwise; empty = show the error
+	FailurePolicy rotation.FailurePolicy `json:"failure_policy,omitempty"`
+	MaxFailures   int                    `json:"max_failures,omitempty"` // disable an app after this many failures in a row; 0 = never
+
+	// Where community apps are installed and indexed from; empty fields
+	// use the Tidbyt community repository
+	CommunitySource CommunitySource `json:"community_source"`
+
+	// Timezone for rotation schedules (IANA name); empty = server local time
+	Timezone string `json:"timezone,omitempty"`
+
 	// Rotation settings
 	RotationEnabled bool              `json:"rotation_enabled"`
 	Apps            []rotation.AppEntry `json:"apps"`
 
 	// Display power
 	PowerOn bool `json:"power_on"`
+
+	// Per-display settings, keyed by display ID
+	Displays map[string]*DisplayConfig `json:"displays,omitempty"`
+}
+
+// CommunitySource holds the community source settings; apps.SourceFromConfig
+// turns them into an apps.Source
+type CommunitySource struct {
+	APIURL  string `json:"api_url,omitempty"` // API base, e.g. https://api.github.com or https://gitea.local/api/v1
+	RawURL  string `json:"raw_url,omitempty"` // file URL template; {repo}, {branch} and {path} are filled in
+	Repo    string `json:"repo,omitempty"`    // owner/name
+	Branch  string `json:"branch,omitempty"`
+	AppsDir string `json:"apps_dir,omitempty"` // directory holding one directory per app
+	Token   string `json:"token,omitempty"`    // sent to the API, for private forks and rate limits
+}
+
+// DisplayConfig holds settings that belong to a single display
+type DisplayConfig struct {
+	Calibration *calibration.Profile `json:"calibration,omitempty"`
+	Output      *output.Target       `json:"output,omitempty"`
+	Timezone    string               `json:"timezone,omitempty"` // overrides Config.Timezone
+	Strategy    string               `json:"strategy,omitempty"` // rotation strategy; empty = sequential
+
+	// Named rotation lists; while one is active it replaces Config.Apps
+	Playlists      map[string][]rotation.AppEntry `json:"playlists,omitempty"`
+	ActivePlaylist string                         `json:"active_playlist,omitempty"`
 }
 
 // DefaultConfig returns a config with sensible defaults
@@ -43,6 +93,7 @@ func DefaultConfig() *Config {
 		DefaultHeight:   32,
 		DefaultDwell:    10000, // 10 seconds
 		Brightness:      80,
+		RenderWorkers:   2,
 		RotationEnabled: true,
 		PowerOn:         true,
 		Apps:            []rotation.AppEntry{},
@@ -69,10 +120,40 @@ func Load(path string) (*Config, error) {
 	if err := json.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("parsing config: %w", err)
 	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
 
 	return cfg, nil
 }
 
+// validate checks the settings that would otherwise silently fall back to a
+// default when misspelled
+func (c *Config) validate() error {
+	if err := c.FailurePolicy.Validate(); err != nil {
+		return fmt.Errorf("failure_policy: %w", err)
+	}
+	if c.MaxFailures < 0 {
+		return fmt.Errorf("max_failures must not be negative")
+	}
+
+	entries := c.Apps
+	for _, dc := range c.Displays {
+		if dc == nil {
+			continue
+		}
+		for _, playlist := range dc.Playlists {
+			entries = append(entries[:len(entries):len(entries)], playlist...)
+		}
+	}
+	for _, app := range entries {
+		if err := app.OnFailure.Validate(); err != nil {
+			return fmt.Errorf("app %s: on_failure: %w", app.ID, err)
+		}
+	}
+	return nil
+}
+
 // Save saves the config to disk
 func (c *Config) Save() error {
 	c.mu.RLock()
@@ -108,6 +189,34 @@ func (c *Config) SetBrightness(brightness int) error {
 	return c.Save()
 }
 
+// GetBrightness returns the fixed brightness
+func (c *Config) GetBrightness() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Brightness
+}
+
+// SetBrightnessSchedule updates the brightness schedule and saves
+func (c *Config) SetBrightnessSchedule(schedule *brightness.Schedule) error {
+	c.mu.Lock()
+	c.BrightnessSchedule = schedule
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// GetBrightnessSchedule returns a copy of the brightness schedule, or nil
+func (c *Config) GetBrightnessSchedule() *brightness.Schedule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.BrightnessSchedule == nil {
+		return nil
+	}
+	schedule := *c.BrightnessSchedule
+	schedule.Points = append([]brightness.Point(nil), c.BrightnessSchedule.Points...)
+	return &schedule
+}
+
 // SetPower updates power state and saves
 func (c *Config) SetPower(on bool) error {
 	c.mu.Lock()
@@ -141,3 +250,58 @@ func (c *Config) GetApps() []rotation.AppEntry {
 	copy(result, c.Apps)
 	return result
 }
+
+// GetDisplay returns a copy of the settings for a display
+func (c *Config) GetDisplay(id string) DisplayConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if dc, ok := c.Displays[id]; ok && dc != nil {
+		result := *dc
+		if dc.Playlists != nil {
+			result.Playlists = make(map[string][]rotation.AppEntry, len(dc.Playlists))
+			for name, apps := range dc.Playlists {
+				result.Playlists[name] = append([]rotation.AppEntry(nil), apps...)
+			}
+		}
+		return result
+	}
+	return DisplayConfig{}
+}
+
+// UpdateDisplay modifies the settings for a display and saves
+func (c *Config) UpdateDisplay(id string, fn func(dc *DisplayConfig)) error {
+	c.mu.Lock()
+	if c.Displays == nil {
+		c.Displays = make(map[string]*DisplayConfig)
+	}
+	dc, ok := c.Displays[id]
+	if !ok || dc == nil {
+		dc = &DisplayConfig{}
+		c.Displays[id] = dc
+	}
+	fn(dc)
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// Location returns the timezone for a display's schedules: its own timezone,
+// else the server timezone, else local time
+func (c *Config) Location(displayID string) *time.Location {
+	c.mu.RLock()
+	name := c.Timezone
+	if dc, ok := c.Displays[displayID]; ok && dc != nil && dc.Timezone != "" {
+		name = dc.Timezone
+	}
+	c.mu.RUnlock()
+
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("Unknown timezone %q for display %s, using local time", name, displayID)
+		return time.Local
+	}
+	return loc
+}
@@ -0,0 +1,97 @@
+package display
+
+import (
+	"log"
+	"time"
+)
+
+// How often the brightness schedule is re-evaluated; short enough that
+// ramps look smooth
+const brightnessCheckInterval = 15 * time.Second
+
+// followBrightnessSchedule keeps the brightness on the configured schedule
+// until the display is stopped
+func (d *Display) followBrightnessSchedule() {
+	ticker := time.NewTicker(brightnessCheckInterval)
+	defer ticker.Stop()
+
+	d.applyBrightnessSchedule()
+	for {
+		select {
+		case <-d.stopCh:
+			return
+		case <-ticker.C:
+			d.applyBrightnessSchedule()
+		}
+	}
+}
+
+// applyBrightnessSchedule sets the scheduled brightness unless a manual
+// override is still in effect
+func (d *Display) applyBrightnessSchedule() {
+	schedule := d.config.GetBrightnessSchedule()
+	if schedule == nil {
+		return
+	}
+
+	now := time.Now().In(d.config.Location(d.ID))
+
+	d.brightnessMu.Lock()
+	if now.Before(d.overrideUntil) {
+		d.brightnessMu.Unlock()
+		return
+	}
+	if !d.overrideUntil.IsZero() {
+		log.Printf("Brightness override on %s expired, following schedule", d.ID)
+		d.overrideUntil = time.Time{}
+	}
+	d.brightnessMu.Unlock()
+
+	level, _ := schedule.Level(now)
+	if level != d.rotation.GetBrightness() {
+		d.applyBrightness(level)
+	}
+}
+
+// startBrightnessOverride holds a manually set brightness until the next
+// scheduled change
+func (d *Display) startBrightnessOverride() {
+	schedule := d.config.GetBrightnessSchedule()
+	if schedule == nil {
+		return
+	}
+
+	_, next := schedule.Level(time.Now().In(d.config.Location(d.ID)))
+
+	d.brightnessMu.Lock()
+	d.overrideUntil = next
+	d.brightnessMu.Unlock()
+}
+
+// BrightnessOverride returns when a manual brightness override ends, or the
+// zero time if the display follows its schedule
+func (d *Display) BrightnessOverride() time.Time {
+	d.brightnessMu.Lock()
+	defer d.brightnessMu.Unlock()
+	return d.overrideUntil
+}
+
+// ResumeBrightnessSchedule drops any manual override and applies the
+// current schedule
+func (d *Display) ResumeBrightnessSchedule() {
+	d.brightnessMu.Lock()
+	d.overrideUntil = time.Time{}
+	d.brightnessMu.Unlock()
+
+	d.applyBrightnessSchedule()
+}
+
+// EndBrightnessSchedule drops any manual override and returns the display
+// to the fixed brightness, after the schedule has been removed
+func (d *Display) EndBrightnessSchedule() {
+	d.brightnessMu.Lock()
+	d.overrideUntil = time.Time{}
+	d.brightnessMu.Unlock()
+
+	d.applyBrightness(d.config.GetBrightness())
+}
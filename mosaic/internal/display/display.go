@@ -1,13 +1,19 @@
 package display
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"image"
 	"log"
+	"maps"
 	"sync"
 	"time"
 
 	"github.com/johnfernkas/mosaic-addon/internal/apps"
+	"github.com/johnfernkas/mosaic-addon/internal/calibration"
 	"github.com/johnfernkas/mosaic-addon/internal/config"
+	"github.com/johnfernkas/mosaic-addon/internal/output"
 	"github.com/johnfernkas/mosaic-addon/internal/pixlet"
 	"github.com/johnfernkas/mosaic-addon/internal/rotation"
 )
@@ -31,6 +37,64 @@ type FrameData struct {
 	Brightness int
 	AppName    string
 	UpdatedAt  time.Time
+
+	encoded *encodingCache
+
+	// Pixels as the app rendered them, before the panel's calibration
+	raw        []byte
+	rawEncoded *encodingCache
+}
+
+// Uncalibrated returns the frame in the colors the app rendered, before the
+// panel's calibration. Previews show this; devices and outputs get Pixels.
+func (f *FrameData) Uncalibrated() *FrameData {
+	if f.raw == nil {
+		return f
+	}
+	frame := *f
+	frame.Pixels = f.raw
+	frame.encoded = f.rawEncoded
+	frame.raw, frame.rawEncoded = nil, nil
+	return &frame
+}
+
+// Images unpacks the RGB pixel buffer into one image per animation frame
+func (f *FrameData) Images() []*image.RGBA {
+	frameSize := f.Width * f.Height * 3
+	if frameSize == 0 {
+		return nil
+	}
+
+	count := f.FrameCount
+	if count < 1 {
+		count = 1
+	}
+	if available := len(f.Pixels) / frameSize; count > available {
+		count = available
+	}
+
+	images := make([]*image.RGBA, 0, count)
+	for i := 0; i < count; i++ {
+		img := image.NewRGBA(image.Rect(0, 0, f.Width, f.Height))
+		src := f.Pixels[i*frameSize : (i+1)*frameSize]
+		for p := 0; p < f.Width*f.Height; p++ {
+			img.Pix[p*4] = src[p*3]
+			img.Pix[p*4+1] = src[p*3+1]
+			img.Pix[p*4+2] = src[p*3+2]
+			img.Pix[p*4+3] = 0xff
+		}
+		images = append(images, img)
+	}
+	return images
+}
+
+// prerender is an upcoming rotation app rendered while the current one dwells
+type prerender struct {
+	app    rotation.AppEntry
+	frame  *pixlet.Frame
+	err    error
+	done   chan struct{} // closed when the render finishes
+	cancel context.CancelFunc
 }
 
 // Display represents a single LED matrix display
@@ -50,6 +114,42 @@ type Display struct {
 
 	// Current frame state
 	currentFrame *FrameData
+	rawPixels    []byte // current frame before calibration
+
+	// Render sequencing: every frame update takes a sequence number and
+	// only the newest one may set the frame
+	renderMu     sync.Mutex
+	renderSeq    uint64
+	renderCancel context.CancelFunc
+
+	// Next rotation app, rendered ahead of its turn
+	prerenderMu sync.Mutex
+	next        *prerender
+
+	// Color calibration for this panel
+	calibrationProfile calibration.Profile
+	calibration        *calibration.Table
+
+	// Frame subscribers (streaming clients) and network push target
+	subMu       sync.Mutex
+	subscribers map[chan *FrameData]struct{}
+	output      *output.Streamer
+
+	// App put on screen by ShowApp while it pauses the rotation
+	pinned *rotation.AppEntry
+
+	// Playlist the rotation was loaded from; empty = the shared app list
+	playlistMu     sync.Mutex
+	activePlaylist string
+
+	// Manual brightness set while a brightness schedule is active; the
+	// schedule takes over again at its next change
+	brightnessMu  sync.Mutex
+	overrideUntil time.Time
+
+	// Render failures and last good frame of each rotation app
+	healthMu sync.Mutex
+	health   map[string]*appHealth
 
 	// Control
 	stopCh chan struct{}
@@ -67,18 +167,70 @@ func NewDisplay(id, name string, width, height int, cfg *config.Config, appRepo
 		rotation: rotation.NewManager(time.Duration(cfg.DefaultDwell) * time.Millisecond),
 		renderer: pixlet.NewRenderer(width, height),
 		stopCh:   make(chan struct{}),
+
+		subscribers: make(map[chan *FrameData]struct{}),
+	}
+
+	// Load per-display calibration
+	d.calibrationProfile = calibration.Default()
+	if profile := cfg.GetDisplay(id).Calibration; profile != nil {
+		if table, err := calibration.NewTable(*profile); err != nil {
+			log.Printf("Ignoring invalid calibration for display %s: %v", id, err)
+		} else {
+			d.calibrationProfile = *profile
+			d.calibration = table
+		}
+	}
+
+	// Bind network push target
+	if target := cfg.GetDisplay(id).Output; target != nil {
+		if streamer, err := output.NewStreamer(*target); err != nil {
+			log.Printf("Failed to start output for display %s: %v", id, err)
+		} else {
+			d.output = streamer
+		}
 	}
 
 	// Set initial state from config
+	d.rotation.SetLocation(cfg.Location(id))
 	d.rotation.SetEnabled(cfg.RotationEnabled)
 	d.rotation.SetBrightness(cfg.Brightness)
+	if strategy, err := rotation.NewStrategy(cfg.GetDisplay(id).Strategy); err != nil {
+		log.Printf("Ignoring rotation strategy for display %s: %v", id, err)
+	} else {
+		d.rotation.SetStrategy(strategy)
+	}
 
-	// Load apps from config
-	d.rotation.SetApps(cfg.GetApps())
+	// Load apps from config, or from the active playlist
+	dc := cfg.GetDisplay(id)
+	if playlist, ok := dc.Playlists[dc.ActivePlaylist]; ok && dc.ActivePlaylist != "" {
+		d.activePlaylist = dc.ActivePlaylist
+		d.rotation.SetApps(playlist)
+	} else {
+		d.rotation.SetApps(cfg.GetApps())
+	}
 
 	// Set callback for when rotation advances
 	d.rotation.OnAdvance(func(app rotation.AppEntry) {
-		d.renderApp(app)
+		// Take the sequence number here, in rotation order, and render
+		// in the background
+		ctx, seq := d.beginRender()
+		go d.showRotationApp(ctx, seq, app)
+	})
+
+	// Notifications interrupt the rotation until they expire
+	d.rotation.OnNotify(func(n rotation.Notification) {
+		ctx, seq := d.beginRender()
+		go d.showNotification(ctx, seq, n)
+	})
+
+	// Notifications ended while the rotation is off: put back what showed
+	d.rotation.OnRestore(d.restoreScreen)
+
+	// Nothing is scheduled right now: fall back to the splash screen
+	d.rotation.OnIdle(func() {
+		ctx, seq := d.beginRender()
+		go d.renderSourceSeq(ctx, seq, "idle", d.startupSource(), nil)
 	})
 
 	// Render initial frame
@@ -90,10 +242,12 @@ func NewDisplay(id, name string, width, height int, cfg *config.Config, appRepo
 // Start begins the rotation loop
 func (d *Display) Start() {
 	go d.rotation.Run()
+	go d.followBrightnessSchedule()
 
 	// Trigger initial render if we have apps
-	if app := d.rotation.CurrentApp(); app != nil {
-		d.renderApp(*app)
+	if app := d.rotation.FirstApp(); app != nil {
+		ctx, seq := d.beginRender()
+		d.showRotationApp(ctx, seq, *app)
 	}
 }
 
@@ -101,6 +255,23 @@ func (d *Display) Start() {
 func (d *Display) Stop() {
 	d.rotation.Stop()
 	close(d.stopCh)
+
+	d.subMu.Lock()
+	if d.output != nil {
+		d.output.Stop()
+		d.output = nil
+	}
+	d.subMu.Unlock()
+}
+
+// SetRenderCache shares a render cache with other displays
+func (d *Display) SetRenderCache(cache *pixlet.Cache) {
+	d.renderer.SetCache(cache)
+}
+
+// SetRenderPool limits renders to the slots of a shared worker pool
+func (d *Display) SetRenderPool(pool *pixlet.Pool) {
+	d.renderer.SetPool(pool)
 }
 
 // GetFrame returns the current frame data
@@ -112,7 +283,84 @@ func (d *Display) GetFrame() *FrameData {
 		return d.generateTestPattern()
 	}
 
-	// Return copy
+	return d.snapshotLocked()
+}
+
+// Subscribe registers for frame updates. The returned channel receives the
+// latest frame whenever it changes; slow readers only ever see the newest
+// frame. Call the returned function to unsubscribe.
+func (d *Display) Subscribe() (<-chan *FrameData, func()) {
+	ch := make(chan *FrameData, 1)
+
+	d.subMu.Lock()
+	d.subscribers[ch] = struct{}{}
+	d.subMu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			d.subMu.Lock()
+			delete(d.subscribers, ch)
+			d.subMu.Unlock()
+		})
+	}
+}
+
+// GetOutput returns the network push target, or nil if none is bound
+func (d *Display) GetOutput() *output.Target {
+	d.subMu.Lock()
+	defer d.subMu.Unlock()
+
+	if d.output == nil {
+		return nil
+	}
+	target := d.output.Target()
+	return &target
+}
+
+// SetOutput binds the display to a network push target (DDP, E1.31 or
+// Art-Net). A nil target unbinds it.
+func (d *Display) SetOutput(target *output.Target) error {
+	var streamer *output.Streamer
+	if target != nil {
+		var err error
+		if streamer, err = output.NewStreamer(*target); err != nil {
+			return err
+		}
+		// Start with the current frame instead of waiting for the next one
+		streamer.Update(toOutputFrame(d.GetFrame()))
+	}
+
+	d.subMu.Lock()
+	if d.output != nil {
+		d.output.Stop()
+	}
+	d.output = streamer
+	d.subMu.Unlock()
+
+	return d.config.UpdateDisplay(d.ID, func(dc *config.DisplayConfig) {
+		if streamer == nil {
+			dc.Output = nil
+		} else {
+			saved := streamer.Target()
+			dc.Output = &saved
+		}
+	})
+}
+
+func toOutputFrame(frame *FrameData) output.Frame {
+	return output.Frame{
+		Pixels:     frame.Pixels,
+		Width:      frame.Width,
+		Height:     frame.Height,
+		FrameCount: frame.FrameCount,
+		DelayMs:    frame.DelayMs,
+		Brightness: frame.Brightness,
+	}
+}
+
+// snapshotLocked returns a copy of the current frame. Caller must hold d.mu.
+func (d *Display) snapshotLocked() *FrameData {
 	return &FrameData{
 		Pixels:     d.currentFrame.Pixels,
 		Width:      d.currentFrame.Width,
@@ -123,15 +371,54 @@ func (d *Display) GetFrame() *FrameData {
 		Brightness: d.rotation.GetBrightness(),
 		AppName:    d.currentFrame.AppName,
 		UpdatedAt:  d.currentFrame.UpdatedAt,
+		encoded:    d.currentFrame.encoded,
+		raw:        d.currentFrame.raw,
+		rawEncoded: d.currentFrame.rawEncoded,
+	}
+}
+
+// publish pushes a frame to all subscribers and the output target without blocking
+func (d *Display) publish(frame *FrameData) {
+	d.subMu.Lock()
+	defer d.subMu.Unlock()
+
+	if d.output != nil {
+		d.output.Update(toOutputFrame(frame))
+	}
+
+	for ch := range d.subscribers {
+		// Drop any frame the subscriber has not picked up yet
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- frame:
+		default:
+		}
 	}
 }
 
-// SetBrightness updates display brightness
+// SetBrightness updates display brightness. While a brightness schedule is
+// active this overrides it until the next scheduled change.
 func (d *Display) SetBrightness(brightness int) error {
-	d.rotation.SetBrightness(brightness)
+	d.startBrightnessOverride()
+	d.applyBrightness(brightness)
 	return d.config.SetBrightness(brightness)
 }
 
+// applyBrightness sets the brightness without saving it
+func (d *Display) applyBrightness(brightness int) {
+	d.rotation.SetBrightness(brightness)
+
+	// Brightness is part of the frame metadata, so let clients know
+	d.mu.RLock()
+	if d.currentFrame != nil {
+		d.publish(d.snapshotLocked())
+	}
+	d.mu.RUnlock()
+}
+
 // GetBrightness returns current brightness
 func (d *Display) GetBrightness() int {
 	return d.rotation.GetBrightness()
@@ -139,6 +426,7 @@ func (d *Display) GetBrightness() int {
 
 // SetPower turns the display on/off
 func (d *Display) SetPower(on bool) error {
+	d.setPinned(nil)
 	if !on {
 		// Turning off: stop rotation and render blank
 		d.rotation.SetEnabled(false)
@@ -157,6 +445,43 @@ func (d *Display) SetPower(on bool) error {
 	return d.config.SetPower(on)
 }
 
+// GetCalibration returns the display's color calibration profile
+func (d *Display) GetCalibration() calibration.Profile {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.calibrationProfile
+}
+
+// SetCalibration validates and applies a color calibration profile.
+// The current frame is re-calibrated right away so changes show immediately.
+func (d *Display) SetCalibration(profile calibration.Profile) error {
+	table, err := calibration.NewTable(profile)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.calibrationProfile = profile
+	d.calibration = table
+	if d.currentFrame != nil && d.rawPixels != nil {
+		frame := *d.currentFrame
+		frame.Pixels = table.Apply(d.rawPixels)
+		frame.UpdatedAt = time.Now()
+		frame.encoded = newEncodingCache()
+		d.currentFrame = &frame
+		d.publish(d.snapshotLocked())
+	}
+	d.mu.Unlock()
+
+	return d.config.UpdateDisplay(d.ID, func(dc *config.DisplayConfig) {
+		if profile.IsIdentity() {
+			dc.Calibration = nil
+		} else {
+			dc.Calibration = &profile
+		}
+	})
+}
+
 // IsPowerOn returns power state
 func (d *Display) IsPowerOn() bool {
 	return d.config.PowerOn
@@ -164,6 +489,7 @@ func (d *Display) IsPowerOn() bool {
 
 // SetRotationEnabled enables/disables rotation
 func (d *Display) SetRotationEnabled(enabled bool) error {
+	d.setPinned(nil)
 	d.rotation.SetEnabled(enabled)
 	return d.config.SetRotationEnabled(enabled)
 }
@@ -186,7 +512,7 @@ func (d *Display) GetRotationApps() []rotation.AppEntry {
 // SetRotationApps sets apps in rotation
 func (d *Display) SetRotationApps(apps []rotation.AppEntry) error {
 	d.rotation.SetApps(apps)
-	return d.config.SetApps(apps)
+	return d.saveRotation()
 }
 
 // AddToRotation adds an app to rotation
@@ -207,7 +533,7 @@ func (d *Display) AddToRotation(appID string) error {
 	d.rotation.AddApp(entry)
 
 	// Update config
-	return d.config.SetApps(d.rotation.GetApps())
+	return d.saveRotation()
 }
 
 // RemoveFromRotation removes an app from rotation
@@ -215,7 +541,75 @@ func (d *Display) RemoveFromRotation(appID string) error {
 	if !d.rotation.RemoveApp(appID) {
 		return fmt.Errorf("app %q not in rotation", appID)
 	}
-	return d.config.SetApps(d.rotation.GetApps())
+	return d.saveRotation()
+}
+
+// UpdateRotationApp modifies a rotation entry (dwell, enabled, schedule).
+// Enabling a disabled entry clears its failure streak.
+func (d *Display) UpdateRotationApp(appID string, fn func(app *rotation.AppEntry)) error {
+	var reenabled bool
+	if !d.rotation.UpdateApp(appID, func(app *rotation.AppEntry) {
+		wasEnabled := app.Enabled
+		fn(app)
+		reenabled = !wasEnabled && app.Enabled
+	}) {
+		return fmt.Errorf("app %q not in rotation", appID)
+	}
+	if reenabled {
+		d.resetFailures(appID)
+	}
+	return d.saveRotation()
+}
+
+// IsScheduled reports whether a rotation entry may show right now
+func (d *Display) IsScheduled(app rotation.AppEntry) bool {
+	return d.rotation.IsScheduled(app)
+}
+
+// GetTimezone returns the timezone rotation schedules use
+func (d *Display) GetTimezone() string {
+	return d.config.Location(d.ID).String()
+}
+
+// SetTimezone sets the display's own timezone for schedules. An empty name
+// falls back to the server timezone.
+func (d *Display) SetTimezone(name string) error {
+	if name != "" {
+		if _, err := time.LoadLocation(name); err != nil {
+			return fmt.Errorf("unknown timezone %q", name)
+		}
+	}
+
+	if err := d.config.UpdateDisplay(d.ID, func(dc *config.DisplayConfig) {
+		dc.Timezone = name
+	}); err != nil {
+		return err
+	}
+
+	d.rotation.SetLocation(d.config.Location(d.ID))
+	return nil
+}
+
+// GetRotationStrategy returns the name of the rotation strategy
+func (d *Display) GetRotationStrategy() string {
+	return d.rotation.Strategy()
+}
+
+// SetRotationStrategy sets how the rotation picks the next app
+func (d *Display) SetRotationStrategy(name string) error {
+	strategy, err := rotation.NewStrategy(name)
+	if err != nil {
+		return err
+	}
+
+	if err := d.config.UpdateDisplay(d.ID, func(dc *config.DisplayConfig) {
+		dc.Strategy = strategy.Name()
+	}); err != nil {
+		return err
+	}
+
+	d.rotation.SetStrategy(strategy)
+	return nil
 }
 
 // ShowApp temporarily shows a specific app
@@ -230,18 +624,25 @@ func (d *Display) ShowApp(appID string, durationSecs int) error {
 	d.rotation.SetEnabled(false)
 
 	// Render the app
-	entry := rotation.AppEntry{
+	entry := &rotation.AppEntry{
 		ID:     appID,
 		Name:   app.Name,
 		Path:   app.Path,
 		Config: app.Config,
 	}
-	d.renderApp(entry)
+	d.setPinned(entry)
+	ctx, seq := d.beginRender()
+	d.renderAppSeq(ctx, seq, *entry, false)
 
 	// Resume after duration
 	if durationSecs > 0 {
 		go func() {
 			time.Sleep(time.Duration(durationSecs) * time.Second)
+			d.mu.Lock()
+			if d.pinned == entry {
+				d.pinned = nil
+			}
+			d.mu.Unlock()
 			d.rotation.SetEnabled(wasEnabled)
 		}()
 	}
@@ -249,69 +650,204 @@ func (d *Display) ShowApp(appID string, durationSecs int) error {
 	return nil
 }
 
+func (d *Display) setPinned(app *rotation.AppEntry) {
+	d.mu.Lock()
+	d.pinned = app
+	d.mu.Unlock()
+}
+
+// restoreScreen puts back what the display showed before notifications
+// while the rotation is off: nothing when powered off, else the app
+// ShowApp put up, else the rotation's current app
+func (d *Display) restoreScreen() {
+	d.mu.RLock()
+	pinned := d.pinned
+	d.mu.RUnlock()
+
+	switch app := d.rotation.CurrentApp(); {
+	case !d.IsPowerOn():
+		d.renderBlankScreen()
+	case pinned != nil:
+		ctx, seq := d.beginRender()
+		go d.renderAppSeq(ctx, seq, *pinned, false)
+	case app != nil:
+		ctx, seq := d.beginRender()
+		go d.showRotationApp(ctx, seq, *app)
+	default:
+		ctx, seq := d.beginRender()
+		go d.renderSourceSeq(ctx, seq, "idle", d.startupSource(), nil)
+	}
+}
+
 // RenderSource renders inline Starlark source
 func (d *Display) RenderSource(appID string, source []byte, config map[string]string) error {
-	frame, err := d.renderer.RenderAppFromSource(appID, source, config)
+	ctx, seq := d.beginRender()
+	return d.renderSourceSeq(ctx, seq, appID, source, config)
+}
+
+func (d *Display) renderSourceSeq(ctx context.Context, seq uint64, appID string, source []byte, config map[string]string) error {
+	frame, err := d.renderer.RenderSourceContext(ctx, appID, source, config)
 	if err != nil {
 		return err
 	}
 
-	pixels := pixlet.ImagesToRGB(frame.Images)
-	d.setFrame(frame, pixels)
+	d.commitFrame(seq, frame)
 	return nil
 }
 
-// PushText displays text notification
-func (d *Display) PushText(text string, color string, durationSecs int, priority rotation.Priority) {
-	// Create a simple text app source
-	if color == "" {
-		color = "#fff"
+// showRotationApp displays a rotation app, using its pre-rendered frame when
+// one is ready, then starts pre-rendering the app after it
+func (d *Display) showRotationApp(ctx context.Context, seq uint64, app rotation.AppEntry) {
+	d.prerenderMu.Lock()
+	p := d.next
+	d.next = nil
+	d.prerenderMu.Unlock()
+
+	if p != nil && sameEntry(p.app, app) {
+		// Still rendering: waiting is never slower than starting over
+		select {
+		case <-p.done:
+		case <-ctx.Done():
+			p.cancel()
+			return
+		}
+		if p.err == nil {
+			d.recordSuccess(app.ID, p.frame)
+			d.commitFrame(seq, p.frame)
+			d.prerenderNext()
+			return
+		}
+		if errors.Is(p.err, pixlet.ErrNoRoots) {
+			d.skipEmpty(app)
+			return
+		}
+		log.Printf("Pre-render of %s failed, rendering live: %v", app.ID, p.err)
+	} else if p != nil {
+		p.cancel()
 	}
 
-	source := fmt.Sprintf(`
-load("render.star", "render")
+	d.renderAppSeq(ctx, seq, app, true)
 
-def main():
-    return render.Root(
-        child = render.Box(
-            width = 64,
-            height = 32,
-            color = "#000",
-            child = render.WrappedText(
-                content = %q,
-                font = "tom-thumb",
-                color = %q,
-                align = "center",
-            ),
-        ),
-    )
-`, text, color)
+	// A newer advance takes care of pre-rendering what comes after it
+	if ctx.Err() == nil {
+		d.prerenderNext()
+	}
+}
 
-	notification := rotation.Notification{
-		ID:       fmt.Sprintf("text-%d", time.Now().UnixNano()),
-		Text:     text,
-		Source:   source,
-		Duration: time.Duration(durationSecs) * time.Second,
-		Priority: priority,
+// prerenderNext starts rendering the upcoming rotation app in the background
+func (d *Display) prerenderNext() {
+	next := d.rotation.NextApp()
+	if next == nil {
+		return
 	}
 
-	d.rotation.PushNotification(notification)
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &prerender{
+		app:    *next,
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
 
-	// For now, render immediately
-	d.RenderSource("notification", []byte(source), nil)
+	d.prerenderMu.Lock()
+	if d.next != nil {
+		d.next.cancel()
+	}
+	d.next = p
+	d.prerenderMu.Unlock()
+
+	go func() {
+		defer cancel()
+		defer close(p.done)
+		p.frame, p.err = d.renderer.RenderAppContext(ctx, p.app.Path, p.app.Config)
+	}()
 }
 
-// renderApp renders an app and updates the frame
+// sameEntry reports whether two rotation entries render the same content
+func sameEntry(a, b rotation.AppEntry) bool {
+	return a.ID == b.ID && a.Path == b.Path && maps.Equal(a.Config, b.Config)
+}
+
+// renderApp renders a rotation app and updates the frame
 func (d *Display) renderApp(app rotation.AppEntry) {
-	frame, err := d.renderer.RenderApp(app.Path, app.Config)
-	if err != nil {
+	ctx, seq := d.beginRender()
+	d.renderAppSeq(ctx, seq, app, true)
+}
+
+// renderAppSeq renders an app for a render started with beginRender. A
+// failure of an app shown by the rotation is handled by its failure policy,
+// which may retry it until a newer render starts. An app shown by hand
+// (rotating false) shows the error instead, leaving the rotation alone.
+func (d *Display) renderAppSeq(ctx context.Context, seq uint64, app rotation.AppEntry, rotating bool) {
+	for attempt := 0; ; attempt++ {
+		frame, err := d.renderer.RenderAppContext(ctx, app.Path, app.Config)
+		if err == nil {
+			d.recordSuccess(app.ID, frame)
+			d.commitFrame(seq, frame)
+			return
+		}
+		if ctx.Err() != nil {
+			// Superseded by a newer render
+			return
+		}
+		if errors.Is(err, pixlet.ErrNoRoots) {
+			if rotating {
+				d.skipEmpty(app)
+			}
+			return
+		}
+
 		log.Printf("Error rendering app %s: %v", app.ID, err)
-		d.renderErrorScreen(app.Name, err)
-		return
+		if !rotating {
+			d.renderErrorScreen(ctx, seq, app.Name, err)
+			return
+		}
+		if !d.handleRenderFailure(ctx, seq, app, err, attempt) {
+			return
+		}
+
+		select {
+		case <-time.After(retryBackoff(attempt)):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// skipEmpty moves the rotation past an app that has nothing to show. The
+// previous frame stays up until the next app renders.
+func (d *Display) skipEmpty(app rotation.AppEntry) {
+	log.Printf("App %s has nothing to show, skipping", app.ID)
+	d.rotation.SkipEmpty(app.ID)
+}
+
+// beginRender cancels any render in progress and returns the context and
+// sequence number for a new one
+func (d *Display) beginRender() (context.Context, uint64) {
+	d.renderMu.Lock()
+	defer d.renderMu.Unlock()
+
+	if d.renderCancel != nil {
+		d.renderCancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.renderSeq++
+	d.renderCancel = cancel
+	return ctx, d.renderSeq
+}
+
+// commitFrame shows a rendered frame unless a newer render has started since
+// seq was taken. Reports whether the frame was shown.
+func (d *Display) commitFrame(seq uint64, frame *pixlet.Frame) bool {
+	d.renderMu.Lock()
+	defer d.renderMu.Unlock()
+
+	if seq != d.renderSeq {
+		return false
 	}
 
-	pixels := pixlet.ImagesToRGB(frame.Images)
-	d.setFrame(frame, pixels)
+	d.setFrame(frame, pixlet.ImagesToRGB(frame.Images))
+	return true
 }
 
 func (d *Display) setFrame(frame *pixlet.Frame, pixels []byte) {
@@ -323,8 +859,9 @@ func (d *Display) setFrame(frame *pixlet.Frame, pixels []byte) {
 		frameCount = len(frame.Images)
 	}
 
+	d.rawPixels = pixels
 	d.currentFrame = &FrameData{
-		Pixels:     pixels,
+		Pixels:     d.calibration.Apply(pixels),
 		Width:      d.Width,
 		Height:     d.Height,
 		FrameCount: frameCount,
@@ -333,48 +870,79 @@ func (d *Display) setFrame(frame *pixlet.Frame, pixels []byte) {
 		Brightness: d.rotation.GetBrightness(),
 		AppName:    frame.AppName,
 		UpdatedAt:  time.Now(),
+		encoded:    newEncodingCache(),
+		raw:        pixels,
+		rawEncoded: newEncodingCache(),
 	}
+
+	d.publish(d.snapshotLocked())
 }
 
 func (d *Display) renderStartupScreen() {
-	source := `
+	d.RenderSource("startup", d.startupSource(), nil)
+}
+
+// startupSource is the MOSAIC splash, also shown while no app is scheduled
+func (d *Display) startupSource() []byte {
+	width, height := d.renderer.CanvasSize()
+
+	// The 6x13 title needs 36x13 pixels; tiny panels get the small font only
+	titleFont := "6x13"
+	if width < 40 || height < 20 {
+		titleFont = "tom-thumb"
+	}
+
+	source := fmt.Sprintf(`
 load("render.star", "render")
 
 def main():
+    children = [
+        render.Text(
+            content = "MOSAIC",
+            font = %q,
+            color = "#0ff",
+        ),
+    ]
+    if %d >= 20:
+        children.append(render.Text(
+            content = "Ready",
+            font = "tom-thumb",
+            color = "#888",
+        ))
+
     return render.Root(
         child = render.Box(
-            width = 64,
-            height = 32,
+            width = %d,
+            height = %d,
             color = "#111",
             child = render.Column(
                 expanded = True,
                 main_align = "center",
                 cross_align = "center",
-                children = [
-                    render.Text(
-                        content = "MOSAIC",
-                        font = "6x13",
-                        color = "#0ff",
-                    ),
-                    render.Text(
-                        content = "Ready",
-                        font = "tom-thumb",
-                        color = "#888",
-                    ),
-                ],
+                children = children,
             ),
         ),
     )
-`
-	d.RenderSource("startup", []byte(source), nil)
+`, titleFont, height, width, height)
+	return []byte(source)
 }
 
 func (d *Display) renderBlankScreen() {
+	// Supersede any render still in progress so it can't turn the panel back on
+	_, seq := d.beginRender()
+
+	d.renderMu.Lock()
+	defer d.renderMu.Unlock()
+	if seq != d.renderSeq {
+		return
+	}
+
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
 	// All black pixels
 	pixels := make([]byte, d.Width*d.Height*3)
+	d.rawPixels = pixels
 	d.currentFrame = &FrameData{
 		Pixels:     pixels,
 		Width:      d.Width,
@@ -385,18 +953,24 @@ func (d *Display) renderBlankScreen() {
 		Brightness: 0,
 		AppName:    "off",
 		UpdatedAt:  time.Now(),
+		encoded:    newEncodingCache(),
 	}
+
+	d.publish(d.snapshotLocked())
 }
 
-func (d *Display) renderErrorScreen(appName string, err error) {
+// renderErrorScreen shows that an app failed to render. It belongs to the
+// failed render's sequence, so it is dropped if a newer render has started.
+func (d *Display) renderErrorScreen(ctx context.Context, seq uint64, appName string, err error) {
+	width, height := d.renderer.CanvasSize()
 	source := fmt.Sprintf(`
 load("render.star", "render")
 
 def main():
     return render.Root(
         child = render.Box(
-            width = 64,
-            height = 32,
+            width = %d,
+            height = %d,
             color = "#300",
             child = render.Column(
                 expanded = True,
@@ -409,7 +983,7 @@ def main():
                         color = "#f00",
                     ),
                     render.Marquee(
-                        width = 60,
+                        width = %d,
                         child = render.Text(
                             content = %q,
                             font = "tom-thumb",
@@ -420,8 +994,10 @@ def main():
             ),
         ),
     )
-`, appName)
-	d.RenderSource("error", []byte(source), nil)
+`, width, height, max(width-4, 1), appName)
+	if err := d.renderSourceSeq(ctx, seq, "error", []byte(source), nil); err != nil && ctx.Err() == nil {
+		log.Printf("Failed to render error screen: %v", err)
+	}
 }
 
 func (d *Display) generateTestPattern() *FrameData {
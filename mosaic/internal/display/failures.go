@@ -0,0 +1,160 @@
+package display
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/johnfernkas/mosaic-addon/internal/pixlet"
+	"github.com/johnfernkas/mosaic-addon/internal/rotation"
+)
+
+// Backoff between retries of a failing app while it is on screen
+const (
+	retryMinBackoff = 2 * time.Second
+	retryMaxBackoff = time.Minute
+)
+
+// FailureStatus describes an app's recent render failures
+type FailureStatus struct {
+	Streak       int       `json:"streak"` // failed showings in a row
+	LastError    string    `json:"last_error,omitempty"`
+	LastFailure  time.Time `json:"last_failure,omitempty"`
+	AutoDisabled bool      `json:"auto_disabled,omitempty"`
+}
+
+// appHealth is what a display remembers about rendering one app
+type appHealth struct {
+	FailureStatus
+	lastGood *pixlet.Frame
+}
+
+// GetFailures returns the failure status of every app that has failed
+func (d *Display) GetFailures() map[string]FailureStatus {
+	d.healthMu.Lock()
+	defer d.healthMu.Unlock()
+
+	result := make(map[string]FailureStatus)
+	for id, h := range d.health {
+		if h.LastError != "" {
+			result[id] = h.FailureStatus
+		}
+	}
+	return result
+}
+
+// healthLocked returns the health record for an app, creating it if needed.
+// Caller must hold d.healthMu.
+func (d *Display) healthLocked(appID string) *appHealth {
+	if d.health == nil {
+		d.health = make(map[string]*appHealth)
+	}
+	h, ok := d.health[appID]
+	if !ok {
+		h = &appHealth{}
+		d.health[appID] = h
+	}
+	return h
+}
+
+// recordSuccess ends an app's failure streak and keeps the frame to fall
+// back on. The last error stays visible.
+func (d *Display) recordSuccess(appID string, frame *pixlet.Frame) {
+	d.healthMu.Lock()
+	defer d.healthMu.Unlock()
+
+	h := d.healthLocked(appID)
+	h.Streak = 0
+	h.AutoDisabled = false
+	h.lastGood = frame
+}
+
+// resetFailures forgets an app's failure streak, e.g. when it is enabled
+// again by hand
+func (d *Display) resetFailures(appID string) {
+	d.healthMu.Lock()
+	defer d.healthMu.Unlock()
+
+	if h, ok := d.health[appID]; ok {
+		h.Streak = 0
+		h.AutoDisabled = false
+	}
+}
+
+// handleRenderFailure records a failed render and applies the app's failure
+// policy. It reports whether the render should be retried.
+func (d *Display) handleRenderFailure(ctx context.Context, seq uint64, app rotation.AppEntry, err error, attempt int) bool {
+	d.healthMu.Lock()
+	h := d.healthLocked(app.ID)
+	h.LastError = err.Error()
+	h.LastFailure = time.Now()
+	// Retries within one showing count once
+	if attempt == 0 {
+		h.Streak++
+	}
+	streak, lastGood := h.Streak, h.lastGood
+	disable := false
+	if limit := d.maxFailures(app); limit > 0 && streak >= limit && !h.AutoDisabled {
+		h.AutoDisabled = true
+		disable = true
+	}
+	d.healthMu.Unlock()
+
+	if disable {
+		log.Printf("Disabling app %s after %d failures in a row", app.ID, streak)
+		d.rotation.UpdateApp(app.ID, func(entry *rotation.AppEntry) {
+			entry.Enabled = false
+		})
+		if err := d.saveRotation(); err != nil {
+			log.Printf("Failed to save rotation: %v", err)
+		}
+		d.rotation.SkipEmpty(app.ID)
+		return false
+	}
+
+	switch d.failurePolicy(app) {
+	case rotation.FailureRetry:
+		// Keep something of the app's up while retrying
+		if attempt == 0 && lastGood != nil {
+			d.commitFrame(seq, lastGood)
+		}
+		return true
+	case rotation.FailureLastGood:
+		if lastGood != nil {
+			d.commitFrame(seq, lastGood)
+			return false
+		}
+		// Nothing to fall back on yet
+		d.rotation.SkipEmpty(app.ID)
+	case rotation.FailureSkip:
+		d.rotation.SkipEmpty(app.ID)
+	default:
+		d.renderErrorScreen(ctx, seq, app.Name, err)
+	}
+	return false
+}
+
+// failurePolicy returns the policy for an app, falling back to the global one
+func (d *Display) failurePolicy(app rotation.AppEntry) rotation.FailurePolicy {
+	if app.OnFailure != "" {
+		return app.OnFailure
+	}
+	return d.config.FailurePolicy
+}
+
+// maxFailures returns how many failures in a row disable an app; 0 = never
+func (d *Display) maxFailures(app rotation.AppEntry) int {
+	if app.MaxFailures > 0 {
+		return app.MaxFailures
+	}
+	return d.config.MaxFailures
+}
+
+// retryBackoff returns how long to wait before retry attempt+1
+func retryBackoff(attempt int) time.Duration {
+	backoff := retryMinBackoff
+	for i := 0; i < attempt && backoff < retryMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, retryMaxBackoff)
+}
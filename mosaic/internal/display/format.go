@@ -0,0 +1,166 @@
+package display
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// PixelFormat is the color layout of each pixel sent to clients
+type PixelFormat string
+
+const (
+	FormatRGB888 PixelFormat = "rgb888" // 3 bytes per pixel: R, G, B
+	FormatRGB565 PixelFormat = "rgb565" // 2 bytes per pixel, little-endian
+)
+
+// PixelEncoding is how the pixel buffer is packed on the wire
+type PixelEncoding string
+
+const (
+	EncodingNone    PixelEncoding = "none"
+	EncodingZlib    PixelEncoding = "zlib"    // zlib stream (RFC 1950)
+	EncodingDeflate PixelEncoding = "deflate" // raw deflate (RFC 1951)
+	// EncodingDelta XORs every frame after the first with the one before it
+	// and zlib-compresses the result, so unchanged pixels cost almost nothing
+	EncodingDelta PixelEncoding = "delta"
+)
+
+// ParsePixelFormat validates a format name; empty means rgb888
+func ParsePixelFormat(s string) (PixelFormat, error) {
+	switch PixelFormat(s) {
+	case "", FormatRGB888:
+		return FormatRGB888, nil
+	case FormatRGB565:
+		return FormatRGB565, nil
+	}
+	return "", fmt.Errorf("unsupported format %q (use rgb888 or rgb565)", s)
+}
+
+// ParsePixelEncoding validates an encoding name; empty means none
+func ParsePixelEncoding(s string) (PixelEncoding, error) {
+	switch PixelEncoding(s) {
+	case "", EncodingNone:
+		return EncodingNone, nil
+	case EncodingZlib, EncodingDeflate, EncodingDelta:
+		return PixelEncoding(s), nil
+	}
+	return "", fmt.Errorf("unsupported encoding %q (use none, zlib, deflate or delta)", s)
+}
+
+// encodingCache holds encoded pixel buffers for one frame. It is shared by
+// all copies of a FrameData so repeated polls reuse the same encoding.
+type encodingCache struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+func newEncodingCache() *encodingCache {
+	return &encodingCache{entries: make(map[string][]byte)}
+}
+
+// Encode returns the frame pixels in the given format and encoding.
+// Results are cached for the lifetime of the frame.
+func (f *FrameData) Encode(format PixelFormat, encoding PixelEncoding) ([]byte, error) {
+	if format == FormatRGB888 && encoding == EncodingNone {
+		return f.Pixels, nil
+	}
+
+	key := string(format) + "/" + string(encoding)
+	if f.encoded != nil {
+		f.encoded.mu.Lock()
+		defer f.encoded.mu.Unlock()
+		if data, ok := f.encoded.entries[key]; ok {
+			return data, nil
+		}
+	}
+
+	data, err := encodePixels(f.Pixels, f.Width*f.Height, format, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.encoded != nil {
+		f.encoded.entries[key] = data
+	}
+	return data, nil
+}
+
+func encodePixels(rgb []byte, pixelsPerFrame int, format PixelFormat, encoding PixelEncoding) ([]byte, error) {
+	data := rgb
+	bytesPerPixel := 3
+
+	if format == FormatRGB565 {
+		data = toRGB565(rgb)
+		bytesPerPixel = 2
+	}
+
+	switch encoding {
+	case EncodingNone:
+		return data, nil
+	case EncodingZlib:
+		return compress(data, false)
+	case EncodingDeflate:
+		return compress(data, true)
+	case EncodingDelta:
+		return compress(deltaFrames(data, pixelsPerFrame*bytesPerPixel), false)
+	}
+	return nil, fmt.Errorf("unsupported encoding %q", encoding)
+}
+
+// toRGB565 packs 24-bit RGB into 16-bit little-endian RGB565
+func toRGB565(rgb []byte) []byte {
+	out := make([]byte, len(rgb)/3*2)
+	for i, o := 0, 0; i+2 < len(rgb); i, o = i+3, o+2 {
+		v := uint16(rgb[i]>>3)<<11 | uint16(rgb[i+1]>>2)<<5 | uint16(rgb[i+2]>>3)
+		out[o] = byte(v)
+		out[o+1] = byte(v >> 8)
+	}
+	return out
+}
+
+// deltaFrames XORs each frame with the previous one; the first frame is kept as-is
+func deltaFrames(data []byte, frameSize int) []byte {
+	out := make([]byte, len(data))
+	copy(out, data)
+	if frameSize <= 0 {
+		return out
+	}
+
+	for offset := frameSize; offset+frameSize <= len(data); offset += frameSize {
+		for i := 0; i < frameSize; i++ {
+			out[offset+i] = data[offset+i] ^ data[offset-frameSize+i]
+		}
+	}
+	return out
+}
+
+func compress(data []byte, raw bool) ([]byte, error) {
+	var buf bytes.Buffer
+
+	var w io.WriteCloser
+	if raw {
+		fw, err := flate.NewWriter(&buf, flate.BestCompression)
+		if err != nil {
+			return nil, fmt.Errorf("creating deflate writer: %w", err)
+		}
+		w = fw
+	} else {
+		zw, err := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+		if err != nil {
+			return nil, fmt.Errorf("creating zlib writer: %w", err)
+		}
+		w = zw
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("compressing pixels: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("compressing pixels: %w", err)
+	}
+	return buf.Bytes(), nil
+}
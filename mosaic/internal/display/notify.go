@@ -0,0 +1,187 @@
+package display
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"time"
+
+	"github.com/johnfernkas/mosaic-addon/internal/pixlet"
+	"github.com/johnfernkas/mosaic-addon/internal/rotation"
+)
+
+// PushText queues a text notification and returns its ID
+func (d *Display) PushText(text string, color string, durationSecs int, priority rotation.Priority) string {
+	id, _ := d.PushNotification(context.Background(), NotifyOptions{Text: text, Color: color}, durationSecs, priority)
+	return id
+}
+
+// PushNotification queues a templated notification and returns its ID. A
+// URL icon is fetched now, so the notification shows without delay later.
+func (d *Display) PushNotification(ctx context.Context, opts NotifyOptions, durationSecs int, priority rotation.Priority) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+	if opts.Icon != "" {
+		icon, err := loadIcon(ctx, opts.Icon)
+		if err != nil {
+			return "", err
+		}
+		opts.Icon = icon
+	}
+
+	notification := rotation.Notification{
+		ID:       fmt.Sprintf("text-%d", time.Now().UnixNano()),
+		Text:     opts.Text,
+		Color:    opts.Color,
+		Source:   d.notifySource(opts),
+		Config:   opts.config(),
+		Duration: time.Duration(durationSecs) * time.Second,
+		Priority: priority,
+	}
+
+	// The rotation loop puts it on screen when its turn comes
+	d.rotation.PushNotification(notification)
+	return notification.ID, nil
+}
+
+// PushAppNotification queues an installed app as a notification and returns
+// its ID. config overrides the app's saved config for this showing only.
+func (d *Display) PushAppNotification(appID string, config map[string]string, durationSecs int, priority rotation.Priority) (string, error) {
+	app := d.apps.Get(appID)
+	if app == nil {
+		return "", fmt.Errorf("app %q not installed", appID)
+	}
+
+	merged := maps.Clone(app.Config)
+	if merged == nil {
+		merged = make(map[string]string)
+	}
+	maps.Copy(merged, config)
+
+	notification := rotation.Notification{
+		ID:       fmt.Sprintf("app-%d", time.Now().UnixNano()),
+		Text:     app.Name,
+		AppID:    appID,
+		Config:   merged,
+		Duration: time.Duration(durationSecs) * time.Second,
+		Priority: priority,
+	}
+
+	d.rotation.PushNotification(notification)
+	return notification.ID, nil
+}
+
+// GetNotifications returns the queued notifications in the order they show
+func (d *Display) GetNotifications() []rotation.NotificationStatus {
+	return d.rotation.GetNotifications()
+}
+
+// GetNotification returns a queued notification by ID
+func (d *Display) GetNotification(id string) (*rotation.NotificationStatus, bool) {
+	return d.rotation.GetNotification(id)
+}
+
+// DismissNotification removes a notification, taking it off screen if it is
+// showing
+func (d *Display) DismissNotification(id string) error {
+	if !d.rotation.DismissNotification(id) {
+		return fmt.Errorf("notification %q not found", id)
+	}
+	return nil
+}
+
+// ClearNotifications removes queued notifications. Sticky ones are kept
+// unless includeSticky is set.
+func (d *Display) ClearNotifications(includeSticky bool) {
+	if includeSticky {
+		d.rotation.ClearAllNotifications()
+	} else {
+		d.rotation.ClearNotifications()
+	}
+}
+
+// UpdateNotification changes the text or color of a text notification, or
+// how long it stays on screen. remainingSecs replaces the time left;
+// extendSecs adds to it.
+func (d *Display) UpdateNotification(id string, text, color *string, remainingSecs *int, extendSecs int) (*rotation.NotificationStatus, error) {
+	current, ok := d.rotation.GetNotification(id)
+	if !ok {
+		return nil, fmt.Errorf("notification %q not found", id)
+	}
+
+	update := rotation.NotificationUpdate{
+		Text:   text,
+		Color:  color,
+		Extend: time.Duration(extendSecs) * time.Second,
+	}
+	if text != nil || color != nil {
+		if current.AppID != "" {
+			return nil, fmt.Errorf("notification %q shows an app; only its duration can change", id)
+		}
+
+		// Lay the template out again with the new text
+		opts := notifyOptionsFromConfig(current.Config)
+		if text != nil {
+			opts.Text = *text
+		}
+		if color != nil {
+			opts.Color = *color
+		}
+		if err := opts.Validate(); err != nil {
+			return nil, err
+		}
+		source := d.notifySource(opts)
+		update.Source = &source
+		update.Config = opts.config()
+	}
+	if remainingSecs != nil {
+		remaining := time.Duration(*remainingSecs) * time.Second
+		update.Remaining = &remaining
+	}
+
+	status, ok := d.rotation.UpdateNotification(id, update)
+	if !ok {
+		return nil, fmt.Errorf("notification %q not found", id)
+	}
+	return status, nil
+}
+
+// showNotification renders a notification the rotation has put on screen.
+// Scrolling notifications stay up until they have scrolled through once.
+func (d *Display) showNotification(ctx context.Context, seq uint64, n rotation.Notification) {
+	frame, err := d.renderNotification(ctx, n)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, pixlet.ErrNoRoots) {
+			// An app with nothing to say has no business interrupting
+			log.Printf("Notification %s has nothing to show, dismissing", n.ID)
+			d.rotation.DismissNotification(n.ID)
+			return
+		}
+		log.Printf("Failed to render notification %s: %v", n.ID, err)
+		d.renderErrorScreen(ctx, seq, "notification", err)
+		return
+	}
+
+	if d.commitFrame(seq, frame) && len(frame.Images) > 1 {
+		d.rotation.HoldNotification(n.ID, time.Duration(len(frame.Images)*frame.DelayMs)*time.Millisecond)
+	}
+}
+
+func (d *Display) renderNotification(ctx context.Context, n rotation.Notification) (*pixlet.Frame, error) {
+	if n.AppID == "" {
+		return d.renderer.RenderSourceContext(ctx, "notification", []byte(n.Source), n.Config)
+	}
+
+	// Look the app up now, it may have been updated or removed since
+	app := d.apps.Get(n.AppID)
+	if app == nil {
+		return nil, fmt.Errorf("app %q not installed", n.AppID)
+	}
+	return d.renderer.RenderAppContext(ctx, app.Path, n.Config)
+}
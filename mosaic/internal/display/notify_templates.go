@@ -0,0 +1,410 @@
+package display
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Notification templates
+const (
+	TemplateText      = "text"       // text, wrapped or scrolling
+	TemplateIconText  = "icon_text"  // icon on the left, text on the right
+	TemplateTitleBody = "title_body" // title line over a body
+	TemplateProgress  = "progress"   // text over a progress bar
+	TemplateBigNumber = "big_number" // one large value with an optional title
+)
+
+// Scroll modes for the body text
+const (
+	ScrollAuto   = ""       // scroll only when the text doesn't fit
+	ScrollAlways = "always" // always a single scrolling line
+	ScrollNever  = "never"  // always wrapped, even if cut off
+)
+
+const (
+	maxIconBytes     = 1 << 20
+	iconFetchTimeout = 10 * time.Second
+	maxIconRedirects = 5
+)
+
+// iconClient fetches URL icons. Redirects must stay on http(s).
+var iconClient = &http.Client{
+	Timeout: iconFetchTimeout,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxIconRedirects {
+			return fmt.Errorf("too many redirects")
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+		}
+		return nil
+	},
+}
+
+// fontMetrics are approximate glyph sizes, used to decide what fits
+var fontMetrics = map[string]struct{ width, height int }{
+	"tom-thumb":         {4, 6},
+	"CG-pixel-3x5-mono": {4, 6},
+	"CG-pixel-4x5-mono": {5, 6},
+	"tb-8":              {5, 8},
+	"5x8":               {5, 8},
+	"6x10":              {6, 10},
+	"6x10-rounded":      {6, 10},
+	"Dina_r400-6":       {6, 10},
+	"6x13":              {6, 13},
+	"terminus-12":       {6, 12},
+	"terminus-16":       {8, 16},
+	"10x20":             {10, 20},
+}
+
+// NotifyOptions describes a templated notification
+type NotifyOptions struct {
+	Template   string  // one of the Template* constants; empty = text
+	Text       string  // body text, or the value for big_number
+	Title      string  // title_body, progress and big_number
+	Icon       string  // PNG or GIF as base64 (optionally a data: URI) or an http(s) URL
+	Font       string  // body font; empty picks one for the display size
+	TitleFont  string  // title font; empty picks one for the display size
+	Color      string  // body color
+	TitleColor string  // title color
+	Background string  // background color
+	Progress   float64 // 0-100 for the progress template
+	Scroll     string  // one of the Scroll* constants
+}
+
+// Validate checks the template, fonts, colors and progress value
+func (o *NotifyOptions) Validate() error {
+	switch o.Template {
+	case "", TemplateText, TemplateTitleBody, TemplateBigNumber:
+	case TemplateIconText:
+		if o.Icon == "" {
+			return fmt.Errorf("icon_text needs an icon")
+		}
+	case TemplateProgress:
+		if o.Progress < 0 || o.Progress > 100 {
+			return fmt.Errorf("progress must be between 0 and 100")
+		}
+	default:
+		return fmt.Errorf("unknown template %q (use text, icon_text, title_body, progress or big_number)", o.Template)
+	}
+
+	for _, font := range []string{o.Font, o.TitleFont} {
+		if _, ok := fontMetrics[font]; font != "" && !ok {
+			return fmt.Errorf("unknown font %q", font)
+		}
+	}
+	for _, color := range []string{o.Color, o.TitleColor, o.Background} {
+		if color != "" && !validColor(color) {
+			return fmt.Errorf("invalid color %q (use #rgb or #rrggbb)", color)
+		}
+	}
+	switch o.Scroll {
+	case ScrollAuto, ScrollAlways, ScrollNever:
+	default:
+		return fmt.Errorf("invalid scroll %q (use always or never)", o.Scroll)
+	}
+	return nil
+}
+
+// config stores the options in a notification's config so the notification
+// can be laid out again when it is updated. The icon is also read from here
+// by the template source.
+func (o *NotifyOptions) config() map[string]string {
+	config := map[string]string{
+		"template":    o.Template,
+		"text":        o.Text,
+		"title":       o.Title,
+		"font":        o.Font,
+		"title_font":  o.TitleFont,
+		"color":       o.Color,
+		"title_color": o.TitleColor,
+		"background":  o.Background,
+		"scroll":      o.Scroll,
+	}
+	if o.Icon != "" {
+		config["icon"] = o.Icon
+	}
+	if o.Template == TemplateProgress {
+		config["progress"] = strconv.FormatFloat(o.Progress, 'f', -1, 64)
+	}
+	return config
+}
+
+func notifyOptionsFromConfig(config map[string]string) NotifyOptions {
+	progress, _ := strconv.ParseFloat(config["progress"], 64)
+	return NotifyOptions{
+		Template:   config["template"],
+		Text:       config["text"],
+		Title:      config["title"],
+		Icon:       config["icon"],
+		Font:       config["font"],
+		TitleFont:  config["title_font"],
+		Color:      config["color"],
+		TitleColor: config["title_color"],
+		Background: config["background"],
+		Progress:   progress,
+		Scroll:     config["scroll"],
+	}
+}
+
+// loadIcon resolves an icon given as a URL or base64 data to base64-encoded
+// PNG or GIF bytes
+func loadIcon(ctx context.Context, icon string) (string, error) {
+	var data []byte
+	if strings.HasPrefix(icon, "http://") || strings.HasPrefix(icon, "https://") {
+		u, err := url.Parse(icon)
+		if err != nil || u.Host == "" {
+			return "", fmt.Errorf("invalid icon URL")
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return "", fmt.Errorf("invalid icon URL: %w", err)
+		}
+		resp, err := iconClient.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("fetching icon: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("fetching icon: HTTP %d", resp.StatusCode)
+		}
+		if resp.ContentLength > maxIconBytes {
+			return "", fmt.Errorf("icon is larger than %d bytes", maxIconBytes)
+		}
+		if data, err = io.ReadAll(io.LimitReader(resp.Body, maxIconBytes+1)); err != nil {
+			return "", fmt.Errorf("fetching icon: %w", err)
+		}
+	} else {
+		// Accept data URIs as well as bare base64
+		if i := strings.Index(icon, ";base64,"); strings.HasPrefix(icon, "data:") && i >= 0 {
+			icon = icon[i+len(";base64,"):]
+		}
+		var err error
+		if data, err = base64.StdEncoding.DecodeString(strings.TrimSpace(icon)); err != nil {
+			return "", fmt.Errorf("icon is not valid base64")
+		}
+	}
+
+	if len(data) > maxIconBytes {
+		return "", fmt.Errorf("icon is larger than %d bytes", maxIconBytes)
+	}
+	switch http.DetectContentType(data) {
+	case "image/png", "image/gif":
+	default:
+		return "", fmt.Errorf("icon must be a PNG or GIF")
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// notifySource builds the Starlark for a templated notification, laid out for
+// the display's canvas
+func (d *Display) notifySource(o NotifyOptions) string {
+	width, height := d.renderer.CanvasSize()
+
+	color := orDefault(o.Color, "#fff")
+	titleColor := orDefault(o.TitleColor, "#0ff")
+	background := orDefault(o.Background, "#000")
+	bodyFont := orDefault(o.Font, "tom-thumb")
+
+	// Larger panels get a larger title font
+	titleFont := o.TitleFont
+	if titleFont == "" {
+		titleFont = "tom-thumb"
+		if height >= 24 && width >= 48 {
+			titleFont = "tb-8"
+		}
+	}
+	titleHeight := fontMetrics[titleFont].height
+
+	var child string
+	switch o.Template {
+	case TemplateIconText:
+		iconSize := max(min(height, width/4), 8)
+		textWidth := max(width-iconSize-2, 1)
+		child = fmt.Sprintf(`render.Row(
+            expanded = True,
+            cross_align = "center",
+            children = [
+                render.Padding(
+                    pad = (1, 0, 1, 0),
+                    child = render.Image(src = base64.decode(config.get("icon")), width = %d, height = %d),
+                ),
+                render.Box(width = %d, height = %d, child = %s),
+            ],
+        )`, iconSize, iconSize, textWidth, height, bodyText(o.Text, bodyFont, color, textWidth, height, o.Scroll))
+
+	case TemplateTitleBody:
+		bodyHeight := max(height-titleHeight-1, 1)
+		child = fmt.Sprintf(`render.Column(
+            expanded = True,
+            children = [
+                render.Box(width = %d, height = %d, child = %s),
+                render.Box(width = %d, height = %d, child = %s),
+            ],
+        )`, width, titleHeight+1, lineText(o.Title, titleFont, titleColor, width),
+			width, bodyHeight, bodyText(o.Text, bodyFont, color, width, bodyHeight, o.Scroll))
+
+	case TemplateProgress:
+		barWidth := max(width-4, 1)
+		barHeight := max(height/8, 2)
+		filled := int(float64(barWidth) * o.Progress / 100)
+
+		label := o.Text
+		if label == "" {
+			label = fmt.Sprintf("%.0f%%", o.Progress)
+		}
+		labelHeight := max(height-barHeight-3, 1)
+
+		bar := fmt.Sprintf(`render.Box(width = %d, height = %d, color = "#333")`, barWidth, barHeight)
+		// A zero width Box would fill the bar, so leave it out
+		if filled > 0 {
+			bar = fmt.Sprintf(`render.Stack(children = [
+                    %s,
+                    render.Box(width = %d, height = %d, color = %q),
+                ])`, bar, filled, barHeight, color)
+		}
+		child = fmt.Sprintf(`render.Column(
+            expanded = True,
+            main_align = "space_evenly",
+            cross_align = "center",
+            children = [
+                render.Box(width = %d, height = %d, child = %s),
+                %s,
+            ],
+        )`, width, labelHeight, bodyText(label, bodyFont, color, width, labelHeight, o.Scroll), bar)
+
+	case TemplateBigNumber:
+		available := height
+		if o.Title != "" {
+			available -= titleHeight + 1
+		}
+		font := o.Font
+		if font == "" {
+			font = largestFont(o.Text, width, available)
+		}
+		children := []string{lineText(o.Text, font, color, width)}
+		if o.Title != "" {
+			children = append([]string{lineText(o.Title, titleFont, titleColor, width)}, children...)
+		}
+		child = fmt.Sprintf(`render.Column(
+            expanded = True,
+            main_align = "space_evenly",
+            cross_align = "center",
+            children = [%s],
+        )`, strings.Join(children, ", "))
+
+	default:
+		child = bodyText(o.Text, bodyFont, color, width, height, o.Scroll)
+	}
+
+	return fmt.Sprintf(`
+load("render.star", "render")
+load("encoding/base64.star", "base64")
+
+def main(config):
+    return render.Root(
+        child = render.Box(
+            width = %d,
+            height = %d,
+            color = %q,
+            child = %s,
+        ),
+    )
+`, width, height, background, child)
+}
+
+// bodyText lays out text in a width x height area: wrapped if it fits,
+// otherwise as a single scrolling line
+func bodyText(text, font, color string, width, height int, scroll string) string {
+	m := fontMetrics[font]
+	fits := wrappedLines(text, width/m.width)*m.height <= height
+
+	if scroll == ScrollNever || (scroll == ScrollAuto && fits) {
+		return fmt.Sprintf(`render.WrappedText(content = %q, width = %d, font = %q, color = %q, align = "center")`,
+			text, width, font, color)
+	}
+	return fmt.Sprintf(`render.Marquee(width = %d, child = render.Text(content = %q, font = %q, color = %q))`,
+		width, text, font, color)
+}
+
+// lineText is a single line that scrolls when it is too wide
+func lineText(text, font, color string, width int) string {
+	if textWidth(text, font) <= width {
+		return fmt.Sprintf(`render.Text(content = %q, font = %q, color = %q)`, text, font, color)
+	}
+	return fmt.Sprintf(`render.Marquee(width = %d, child = render.Text(content = %q, font = %q, color = %q))`,
+		width, text, font, color)
+}
+
+// largestFont picks the biggest font the text fits in on one line
+func largestFont(text string, width, height int) string {
+	for _, font := range []string{"10x20", "6x13", "tb-8"} {
+		m := fontMetrics[font]
+		if textWidth(text, font) <= width && m.height <= height {
+			return font
+		}
+	}
+	return "tom-thumb"
+}
+
+func textWidth(text, font string) int {
+	return utf8.RuneCountInString(text) * fontMetrics[font].width
+}
+
+// wrappedLines estimates how many lines text takes when word-wrapped at
+// perLine characters
+func wrappedLines(text string, perLine int) int {
+	if perLine < 1 {
+		return 1 << 16
+	}
+
+	lines, used := 1, 0
+	for _, word := range strings.Fields(text) {
+		n := utf8.RuneCountInString(word)
+		switch {
+		case used == 0:
+			used = n
+		case used+1+n <= perLine:
+			used += 1 + n
+		default:
+			lines++
+			used = n
+		}
+		// Words longer than a line are broken up
+		for used > perLine {
+			lines++
+			used -= perLine
+		}
+	}
+	return lines
+}
+
+func validColor(c string) bool {
+	if !strings.HasPrefix(c, "#") {
+		return false
+	}
+	hex := c[1:]
+	switch len(hex) {
+	case 3, 4, 6, 8:
+	default:
+		return false
+	}
+	_, err := strconv.ParseUint(hex, 16, 64)
+	return err == nil
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
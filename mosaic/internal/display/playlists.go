@@ -0,0 +1,145 @@
+package display
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/johnfernkas/mosaic-addon/internal/config"
+	"github.com/johnfernkas/mosaic-addon/internal/rotation"
+)
+
+// DefaultPlaylist is the shared rotation list in Config.Apps
+const DefaultPlaylist = "default"
+
+var playlistNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 _-]{0,63}$`)
+
+// ValidatePlaylistName checks that a name can be used for a new playlist
+func ValidatePlaylistName(name string) error {
+	if name == DefaultPlaylist {
+		return fmt.Errorf("%q is reserved for the shared rotation", DefaultPlaylist)
+	}
+	if !playlistNameRe.MatchString(name) {
+		return fmt.Errorf("invalid playlist name %q (letters, digits, spaces, - and _, up to 64)", name)
+	}
+	return nil
+}
+
+// ActivePlaylist returns the playlist the rotation is playing, or
+// DefaultPlaylist for the shared list
+func (d *Display) ActivePlaylist() string {
+	d.playlistMu.Lock()
+	defer d.playlistMu.Unlock()
+
+	if d.activePlaylist == "" {
+		return DefaultPlaylist
+	}
+	return d.activePlaylist
+}
+
+// GetPlaylists returns the display's playlists by name
+func (d *Display) GetPlaylists() map[string][]rotation.AppEntry {
+	playlists := d.config.GetDisplay(d.ID).Playlists
+	if playlists == nil {
+		playlists = make(map[string][]rotation.AppEntry)
+	}
+	return playlists
+}
+
+// GetPlaylist returns one playlist; DefaultPlaylist is the shared list
+func (d *Display) GetPlaylist(name string) ([]rotation.AppEntry, bool) {
+	if name == DefaultPlaylist {
+		return d.config.GetApps(), true
+	}
+	apps, ok := d.config.GetDisplay(d.ID).Playlists[name]
+	return apps, ok
+}
+
+// SavePlaylist creates or replaces a playlist. If it is playing, the
+// rotation switches to the new list at once.
+func (d *Display) SavePlaylist(name string, apps []rotation.AppEntry) error {
+	if apps == nil {
+		apps = []rotation.AppEntry{}
+	}
+
+	if name == DefaultPlaylist {
+		if err := d.config.SetApps(apps); err != nil {
+			return err
+		}
+	} else if err := d.config.UpdateDisplay(d.ID, func(dc *config.DisplayConfig) {
+		if dc.Playlists == nil {
+			dc.Playlists = make(map[string][]rotation.AppEntry)
+		}
+		dc.Playlists[name] = apps
+	}); err != nil {
+		return err
+	}
+
+	if d.ActivePlaylist() == name {
+		d.rotation.SetApps(apps)
+	}
+	return nil
+}
+
+// DeletePlaylist removes a playlist. If it is playing, the display goes back
+// to the shared list.
+func (d *Display) DeletePlaylist(name string) error {
+	if name == DefaultPlaylist {
+		return fmt.Errorf("the %q playlist can't be deleted", DefaultPlaylist)
+	}
+	if _, ok := d.GetPlaylist(name); !ok {
+		return fmt.Errorf("playlist %q not found", name)
+	}
+
+	if d.ActivePlaylist() == name {
+		if err := d.ActivatePlaylist(DefaultPlaylist); err != nil {
+			return err
+		}
+	}
+
+	return d.config.UpdateDisplay(d.ID, func(dc *config.DisplayConfig) {
+		delete(dc.Playlists, name)
+	})
+}
+
+// ActivatePlaylist switches the rotation to a playlist and shows its first
+// app right away
+func (d *Display) ActivatePlaylist(name string) error {
+	apps, ok := d.GetPlaylist(name)
+	if !ok {
+		return fmt.Errorf("playlist %q not found", name)
+	}
+
+	active := name
+	if name == DefaultPlaylist {
+		active = ""
+	}
+
+	d.playlistMu.Lock()
+	d.activePlaylist = active
+	d.playlistMu.Unlock()
+
+	if err := d.config.UpdateDisplay(d.ID, func(dc *config.DisplayConfig) {
+		dc.ActivePlaylist = active
+	}); err != nil {
+		return err
+	}
+
+	d.rotation.Restart(apps)
+	return nil
+}
+
+// saveRotation stores the rotation list in the playlist it came from
+func (d *Display) saveRotation() error {
+	apps := d.rotation.GetApps()
+
+	name := d.ActivePlaylist()
+	if name == DefaultPlaylist {
+		return d.config.SetApps(apps)
+	}
+	return d.config.UpdateDisplay(d.ID, func(dc *config.DisplayConfig) {
+		if dc.Playlists == nil {
+			dc.Playlists = make(map[string][]rotation.AppEntry)
+		}
+		dc.Playlists[name] = apps
+	})
+}
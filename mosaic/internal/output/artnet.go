@@ -0,0 +1,66 @@
+package output
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// Art-Net ArtDMX packet layout
+const (
+	artnetHeaderLen     = 18
+	artnetPixelsPerUniv = 170 // 510 of 512 DMX channels
+	artnetOpDMX         = 0x5000
+	artnetProtocolVer   = 14
+)
+
+var artnetID = []byte("Art-Net\x00")
+
+type artnetSender struct {
+	conn     *net.UDPConn
+	universe int
+	seq      byte
+	buf      []byte
+}
+
+func newArtNetSender(conn *net.UDPConn, universe int) *artnetSender {
+	return &artnetSender{
+		conn:     conn,
+		universe: universe,
+		buf:      make([]byte, artnetHeaderLen+artnetPixelsPerUniv*3),
+	}
+}
+
+func (a *artnetSender) send(rgb []byte) error {
+	// Sequence 0 disables reordering on the receiver, so skip it
+	a.seq++
+	if a.seq == 0 {
+		a.seq = 1
+	}
+
+	chunkSize := artnetPixelsPerUniv * 3
+	for offset, universe := 0, a.universe; offset < len(rgb); offset, universe = offset+chunkSize, universe+1 {
+		chunk := rgb[offset:min(offset+chunkSize, len(rgb))]
+
+		// DMX data length must be even
+		length := len(chunk) + len(chunk)%2
+		pkt := a.buf[:artnetHeaderLen+length]
+
+		copy(pkt[0:8], artnetID)
+		binary.LittleEndian.PutUint16(pkt[8:10], artnetOpDMX)
+		binary.BigEndian.PutUint16(pkt[10:12], artnetProtocolVer)
+		pkt[12] = a.seq
+		pkt[13] = 0                            // physical port
+		pkt[14] = byte(universe & 0xff)        // SubUni
+		pkt[15] = byte((universe >> 8) & 0x7f) // Net
+		binary.BigEndian.PutUint16(pkt[16:18], uint16(length))
+		copy(pkt[artnetHeaderLen:], chunk)
+		if length > len(chunk) {
+			pkt[len(pkt)-1] = 0
+		}
+
+		if _, err := a.conn.Write(pkt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package output
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// DDP (Distributed Display Protocol) packet layout
+const (
+	ddpHeaderLen = 10
+	ddpMaxData   = 1440 // 480 RGB pixels, fits a standard MTU
+	ddpVersion1  = 0x40
+	ddpFlagPush  = 0x01
+	ddpTypeRGB24 = 0x0B
+	ddpIDDefault = 0x01
+)
+
+type ddpSender struct {
+	conn *net.UDPConn
+	seq  byte
+	buf  []byte
+}
+
+func newDDPSender(conn *net.UDPConn) *ddpSender {
+	return &ddpSender{
+		conn: conn,
+		buf:  make([]byte, ddpHeaderLen+ddpMaxData),
+	}
+}
+
+func (d *ddpSender) send(rgb []byte) error {
+	// Sequence numbers run 1-15; 0 means "not used"
+	d.seq = d.seq%15 + 1
+
+	for offset := 0; offset < len(rgb); offset += ddpMaxData {
+		end := min(offset+ddpMaxData, len(rgb))
+		chunk := rgb[offset:end]
+
+		flags := byte(ddpVersion1)
+		if end == len(rgb) {
+			// Tell the receiver to display the frame
+			flags |= ddpFlagPush
+		}
+
+		pkt := d.buf[:ddpHeaderLen+len(chunk)]
+		pkt[0] = flags
+		pkt[1] = d.seq
+		pkt[2] = ddpTypeRGB24
+		pkt[3] = ddpIDDefault
+		binary.BigEndian.PutUint32(pkt[4:8], uint32(offset))
+		binary.BigEndian.PutUint16(pkt[8:10], uint16(len(chunk)))
+		copy(pkt[ddpHeaderLen:], chunk)
+
+		if _, err := d.conn.Write(pkt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
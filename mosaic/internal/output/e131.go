@@ -0,0 +1,88 @@
+package output
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"net"
+)
+
+// E1.31 (sACN) packet layout
+const (
+	e131HeaderLen      = 126
+	e131PixelsPerUniv  = 170 // 510 of 512 DMX slots
+	e131DefaultPrio    = 100
+	e131SourceName     = "Mosaic"
+	e131RootVector     = 0x00000004
+	e131FramingVector  = 0x00000002
+	e131DMPVector      = 0x02
+	e131AddressType    = 0xa1
+	e131FlagsAndLength = 0x7000
+)
+
+var acnPacketID = []byte("ASC-E1.17\x00\x00\x00")
+
+type e131Sender struct {
+	conn     *net.UDPConn
+	universe int
+	cid      [16]byte
+	seq      map[int]byte
+	buf      []byte
+}
+
+func newE131Sender(conn *net.UDPConn, universe int) *e131Sender {
+	s := &e131Sender{
+		conn:     conn,
+		universe: universe,
+		seq:      make(map[int]byte),
+		buf:      make([]byte, e131HeaderLen+e131PixelsPerUniv*3),
+	}
+	rand.Read(s.cid[:])
+	return s
+}
+
+func (s *e131Sender) send(rgb []byte) error {
+	chunkSize := e131PixelsPerUniv * 3
+
+	for offset, universe := 0, s.universe; offset < len(rgb); offset, universe = offset+chunkSize, universe+1 {
+		chunk := rgb[offset:min(offset+chunkSize, len(rgb))]
+		pkt := s.buf[:e131HeaderLen+len(chunk)]
+		for i := range pkt[:e131HeaderLen] {
+			pkt[i] = 0
+		}
+
+		// Root layer
+		binary.BigEndian.PutUint16(pkt[0:2], 0x0010) // preamble size
+		binary.BigEndian.PutUint16(pkt[2:4], 0x0000) // postamble size
+		copy(pkt[4:16], acnPacketID)
+		binary.BigEndian.PutUint16(pkt[16:18], uint16(e131FlagsAndLength|(len(pkt)-16)))
+		binary.BigEndian.PutUint32(pkt[18:22], e131RootVector)
+		copy(pkt[22:38], s.cid[:])
+
+		// Framing layer
+		binary.BigEndian.PutUint16(pkt[38:40], uint16(e131FlagsAndLength|(len(pkt)-38)))
+		binary.BigEndian.PutUint32(pkt[40:44], e131FramingVector)
+		copy(pkt[44:108], e131SourceName)
+		pkt[108] = e131DefaultPrio
+		binary.BigEndian.PutUint16(pkt[109:111], 0) // sync address
+		pkt[111] = s.seq[universe]
+		pkt[112] = 0 // options
+		binary.BigEndian.PutUint16(pkt[113:115], uint16(universe))
+
+		// DMP layer
+		binary.BigEndian.PutUint16(pkt[115:117], uint16(e131FlagsAndLength|(len(pkt)-115)))
+		pkt[117] = e131DMPVector
+		pkt[118] = e131AddressType
+		binary.BigEndian.PutUint16(pkt[119:121], 0x0000) // first property address
+		binary.BigEndian.PutUint16(pkt[121:123], 0x0001) // address increment
+		binary.BigEndian.PutUint16(pkt[123:125], uint16(len(chunk)+1))
+		pkt[125] = 0x00 // DMX start code
+		copy(pkt[e131HeaderLen:], chunk)
+
+		s.seq[universe]++
+
+		if _, err := s.conn.Write(pkt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package output
+
+import "fmt"
+
+// Origin corners for the first LED in the chain
+const (
+	OriginTopLeft     = "top-left"
+	OriginTopRight    = "top-right"
+	OriginBottomLeft  = "bottom-left"
+	OriginBottomRight = "bottom-right"
+)
+
+// Mapping describes how the LED strip is wired through the matrix
+type Mapping struct {
+	// Origin is the corner where the first LED sits (default top-left)
+	Origin string `json:"origin,omitempty"`
+
+	// Serpentine wiring reverses direction on every other row (or column)
+	Serpentine bool `json:"serpentine"`
+
+	// Vertical wiring runs along columns instead of rows
+	Vertical bool `json:"vertical"`
+}
+
+// Validate checks the origin corner
+func (m Mapping) Validate() error {
+	switch m.Origin {
+	case "", OriginTopLeft, OriginTopRight, OriginBottomLeft, OriginBottomRight:
+		return nil
+	}
+	return fmt.Errorf("unsupported origin %q", m.Origin)
+}
+
+// order returns, for each LED in wire order, the index of its source pixel
+func (m Mapping) order(width, height int) []int {
+	order := make([]int, width*height)
+
+	for n := range order {
+		var x, y int
+		if m.Vertical {
+			x, y = n/height, n%height
+			if m.Serpentine && x%2 == 1 {
+				y = height - 1 - y
+			}
+		} else {
+			x, y = n%width, n/width
+			if m.Serpentine && y%2 == 1 {
+				x = width - 1 - x
+			}
+		}
+
+		switch m.Origin {
+		case OriginTopRight:
+			x = width - 1 - x
+		case OriginBottomLeft:
+			y = height - 1 - y
+		case OriginBottomRight:
+			x, y = width-1-x, height-1-y
+		}
+
+		order[n] = y*width + x
+	}
+	return order
+}
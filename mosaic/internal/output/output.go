@@ -0,0 +1,238 @@
+package output
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Protocols supported for network push targets
+const (
+	ProtocolDDP    = "ddp"
+	ProtocolE131   = "e131"
+	ProtocolArtNet = "artnet"
+)
+
+// keepaliveInterval is how often a still frame is resent. Receivers such as
+// WLED drop out of realtime mode when they stop getting packets.
+const keepaliveInterval = time.Second
+
+// Target describes where and how to push frames for a display
+type Target struct {
+	Protocol string  `json:"protocol"`           // "ddp", "e131" or "artnet"
+	Host     string  `json:"host"`               // receiver hostname or IP
+	Port     int     `json:"port,omitempty"`     // 0 = protocol default
+	Universe int     `json:"universe,omitempty"` // first universe (E1.31 / Art-Net)
+	Mapping  Mapping `json:"mapping"`
+}
+
+// Validate checks the target and fills in protocol defaults
+func (t *Target) Validate() error {
+	switch t.Protocol {
+	case ProtocolDDP:
+		if t.Port == 0 {
+			t.Port = 4048
+		}
+	case ProtocolE131:
+		if t.Port == 0 {
+			t.Port = 5568
+		}
+		if t.Universe == 0 {
+			t.Universe = 1
+		}
+		if t.Universe < 1 || t.Universe > 63999 {
+			return fmt.Errorf("e131 universe must be between 1 and 63999")
+		}
+	case ProtocolArtNet:
+		if t.Port == 0 {
+			t.Port = 6454
+		}
+		if t.Universe < 0 || t.Universe > 32767 {
+			return fmt.Errorf("artnet universe must be between 0 and 32767")
+		}
+	default:
+		return fmt.Errorf("unsupported protocol %q (use ddp, e131 or artnet)", t.Protocol)
+	}
+
+	if t.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	if t.Port < 1 || t.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535")
+	}
+	return t.Mapping.Validate()
+}
+
+// Frame is a rendered frame set to push to a target
+type Frame struct {
+	Pixels     []byte // RGB, all frames back to back
+	Width      int
+	Height     int
+	FrameCount int
+	DelayMs    int
+	Brightness int // 0-100, applied before sending
+}
+
+// sender writes one mapped RGB frame to the network
+type sender interface {
+	send(rgb []byte) error
+}
+
+// Streamer pushes frames to a target at the animation's own cadence
+type Streamer struct {
+	target Target
+	conn   *net.UDPConn
+	sender sender
+
+	updateCh chan Frame
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewStreamer validates the target, opens a UDP socket and starts streaming
+func NewStreamer(target Target) (*Streamer, error) {
+	if err := target.Validate(); err != nil {
+		return nil, err
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(target.Host, strconv.Itoa(target.Port)))
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", target.Host, err)
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, fmt.Errorf("opening udp socket: %w", err)
+	}
+
+	s := &Streamer{
+		target:   target,
+		conn:     conn,
+		updateCh: make(chan Frame, 1),
+		stopCh:   make(chan struct{}),
+	}
+
+	switch target.Protocol {
+	case ProtocolDDP:
+		s.sender = newDDPSender(conn)
+	case ProtocolE131:
+		s.sender = newE131Sender(conn, target.Universe)
+	case ProtocolArtNet:
+		s.sender = newArtNetSender(conn, target.Universe)
+	}
+
+	go s.run()
+	return s, nil
+}
+
+// Target returns the streamer's target
+func (s *Streamer) Target() Target {
+	return s.target
+}
+
+// Update replaces the frame being streamed. Never blocks; if the streamer
+// hasn't picked up the previous update yet, it is replaced.
+func (s *Streamer) Update(f Frame) {
+	select {
+	case <-s.updateCh:
+	default:
+	}
+	select {
+	case s.updateCh <- f:
+	default:
+	}
+}
+
+// Stop stops streaming and closes the socket
+func (s *Streamer) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+}
+
+func (s *Streamer) run() {
+	defer s.conn.Close()
+
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	var (
+		frame   Frame
+		mapped  [][]byte
+		index   int
+		hasData bool
+	)
+
+	for {
+		select {
+		case <-s.stopCh:
+			return
+
+		case frame = <-s.updateCh:
+			mapped = mapFrames(frame, s.target.Mapping)
+			index = 0
+			hasData = len(mapped) > 0
+
+		case <-timer.C:
+			if hasData {
+				index = (index + 1) % len(mapped)
+			}
+		}
+
+		if !hasData {
+			continue
+		}
+
+		if err := s.sender.send(mapped[index]); err != nil {
+			log.Printf("Output %s://%s: %v", s.target.Protocol, s.target.Host, err)
+		}
+
+		next := keepaliveInterval
+		if len(mapped) > 1 && frame.DelayMs > 0 {
+			next = time.Duration(frame.DelayMs) * time.Millisecond
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(next)
+	}
+}
+
+// mapFrames splits a frame set into per-frame buffers in LED wire order,
+// scaled by brightness
+func mapFrames(f Frame, m Mapping) [][]byte {
+	frameSize := f.Width * f.Height * 3
+	if frameSize == 0 || len(f.Pixels) < frameSize {
+		return nil
+	}
+
+	count := f.FrameCount
+	if count < 1 || count*frameSize > len(f.Pixels) {
+		count = len(f.Pixels) / frameSize
+	}
+
+	order := m.order(f.Width, f.Height)
+	scale := f.Brightness
+	if scale < 0 || scale > 100 {
+		scale = 100
+	}
+
+	frames := make([][]byte, count)
+	for i := range frames {
+		src := f.Pixels[i*frameSize : (i+1)*frameSize]
+		out := make([]byte, frameSize)
+		for led, px := range order {
+			for c := 0; c < 3; c++ {
+				out[led*3+c] = byte(int(src[px*3+c]) * scale / 100)
+			}
+		}
+		frames[i] = out
+	}
+	return frames
+}
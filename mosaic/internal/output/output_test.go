@@ -0,0 +1,249 @@
+package output
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// listen opens a local UDP listener and returns it with a target pointing at it
+func listen(t *testing.T, protocol string) (*net.UDPConn, Target) {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, Target{
+		Protocol: protocol,
+		Host:     "127.0.0.1",
+		Port:     conn.LocalAddr().(*net.UDPAddr).Port,
+	}
+}
+
+// stream starts a streamer for the target and pushes one frame to it
+func stream(t *testing.T, target Target, f Frame) {
+	t.Helper()
+	s, err := NewStreamer(target)
+	if err != nil {
+		t.Fatalf("NewStreamer: %v", err)
+	}
+	t.Cleanup(s.Stop)
+	s.Update(f)
+}
+
+// readPackets reads n packets from the listener
+func readPackets(t *testing.T, conn *net.UDPConn, n int) [][]byte {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	pkts := make([][]byte, n)
+	buf := make([]byte, 2048)
+	for i := range pkts {
+		size, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("reading packet %d: %v", i, err)
+		}
+		pkts[i] = append([]byte(nil), buf[:size]...)
+	}
+	return pkts
+}
+
+// testFrame returns a single full-brightness frame of distinct pixel values
+func testFrame(width, height int) Frame {
+	pixels := make([]byte, width*height*3)
+	for i := range pixels {
+		pixels[i] = byte(i * 7)
+	}
+	return Frame{Pixels: pixels, Width: width, Height: height, FrameCount: 1, Brightness: 100}
+}
+
+func TestDDP(t *testing.T) {
+	conn, target := listen(t, ProtocolDDP)
+	frame := testFrame(64, 32) // 6144 bytes: four full packets and 384 bytes
+	stream(t, target, frame)
+
+	var data []byte
+	for i, pkt := range readPackets(t, conn, 5) {
+		last := i == 4
+		wantLen := ddpMaxData
+		if last {
+			wantLen = len(frame.Pixels) - 4*ddpMaxData
+		}
+
+		wantFlags := byte(ddpVersion1)
+		if last {
+			wantFlags |= ddpFlagPush
+		}
+		if pkt[0] != wantFlags {
+			t.Errorf("packet %d: flags = %#x, want %#x", i, pkt[0], wantFlags)
+		}
+		if pkt[1] != 1 {
+			t.Errorf("packet %d: sequence = %d, want 1", i, pkt[1])
+		}
+		if pkt[2] != ddpTypeRGB24 || pkt[3] != ddpIDDefault {
+			t.Errorf("packet %d: type/id = %#x/%#x", i, pkt[2], pkt[3])
+		}
+		if offset := binary.BigEndian.Uint32(pkt[4:8]); offset != uint32(i*ddpMaxData) {
+			t.Errorf("packet %d: offset = %d, want %d", i, offset, i*ddpMaxData)
+		}
+		if length := binary.BigEndian.Uint16(pkt[8:10]); int(length) != wantLen || len(pkt) != ddpHeaderLen+wantLen {
+			t.Errorf("packet %d: length = %d (%d bytes), want %d", i, length, len(pkt), wantLen)
+		}
+		data = append(data, pkt[ddpHeaderLen:]...)
+	}
+
+	if !bytes.Equal(data, frame.Pixels) {
+		t.Error("pixel data doesn't match the frame")
+	}
+}
+
+func TestE131(t *testing.T) {
+	conn, target := listen(t, ProtocolE131)
+	target.Universe = 5
+	frame := testFrame(20, 10) // 200 pixels: 170 in the first universe, 30 in the next
+	stream(t, target, frame)
+
+	var data []byte
+	for i, pkt := range readPackets(t, conn, 2) {
+		pixels := []int{170, 30}[i]
+		if len(pkt) != e131HeaderLen+pixels*3 {
+			t.Fatalf("packet %d: %d bytes, want %d", i, len(pkt), e131HeaderLen+pixels*3)
+		}
+
+		if !bytes.Equal(pkt[4:16], acnPacketID) {
+			t.Errorf("packet %d: packet identifier = %q", i, pkt[4:16])
+		}
+		if v := binary.BigEndian.Uint32(pkt[18:22]); v != e131RootVector {
+			t.Errorf("packet %d: root vector = %#x", i, v)
+		}
+		if v := binary.BigEndian.Uint32(pkt[40:44]); v != e131FramingVector {
+			t.Errorf("packet %d: framing vector = %#x", i, v)
+		}
+		if name := string(bytes.TrimRight(pkt[44:108], "\x00")); name != e131SourceName {
+			t.Errorf("packet %d: source name = %q", i, name)
+		}
+		if pkt[108] != e131DefaultPrio {
+			t.Errorf("packet %d: priority = %d", i, pkt[108])
+		}
+		if u := binary.BigEndian.Uint16(pkt[113:115]); int(u) != 5+i {
+			t.Errorf("packet %d: universe = %d, want %d", i, u, 5+i)
+		}
+		if pkt[117] != e131DMPVector || pkt[118] != e131AddressType {
+			t.Errorf("packet %d: DMP vector/address type = %#x/%#x", i, pkt[117], pkt[118])
+		}
+		if count := binary.BigEndian.Uint16(pkt[123:125]); int(count) != pixels*3+1 {
+			t.Errorf("packet %d: property count = %d, want %d", i, count, pixels*3+1)
+		}
+		if pkt[125] != 0 {
+			t.Errorf("packet %d: start code = %d", i, pkt[125])
+		}
+
+		// Flags and lengths of the root, framing and DMP layers
+		for _, at := range []int{16, 38, 115} {
+			if v := binary.BigEndian.Uint16(pkt[at : at+2]); int(v) != e131FlagsAndLength|(len(pkt)-at) {
+				t.Errorf("packet %d: flags and length at %d = %#x", i, at, v)
+			}
+		}
+		data = append(data, pkt[e131HeaderLen:]...)
+	}
+
+	if !bytes.Equal(data, frame.Pixels) {
+		t.Error("pixel data doesn't match the frame")
+	}
+}
+
+func TestArtNet(t *testing.T) {
+	conn, target := listen(t, ProtocolArtNet)
+	target.Universe = 0x1ff   // net 1, sub-net/universe 0xff
+	frame := testFrame(19, 9) // 171 pixels: 170 in the first universe, 1 in the next
+	stream(t, target, frame)
+
+	var data []byte
+	for i, pkt := range readPackets(t, conn, 2) {
+		chunk := []int{510, 3}[i]
+		length := chunk + chunk%2 // DMX data length is padded to even
+		if len(pkt) != artnetHeaderLen+length {
+			t.Fatalf("packet %d: %d bytes, want %d", i, len(pkt), artnetHeaderLen+length)
+		}
+
+		if !bytes.Equal(pkt[0:8], artnetID) {
+			t.Errorf("packet %d: ID = %q", i, pkt[0:8])
+		}
+		if op := binary.LittleEndian.Uint16(pkt[8:10]); op != artnetOpDMX {
+			t.Errorf("packet %d: opcode = %#x", i, op)
+		}
+		if v := binary.BigEndian.Uint16(pkt[10:12]); v != artnetProtocolVer {
+			t.Errorf("packet %d: protocol version = %d", i, v)
+		}
+		if pkt[12] != 1 {
+			t.Errorf("packet %d: sequence = %d, want 1", i, pkt[12])
+		}
+		universe := target.Universe + i
+		if pkt[14] != byte(universe&0xff) || pkt[15] != byte(universe>>8) {
+			t.Errorf("packet %d: universe = %d/%d, want %d", i, pkt[15], pkt[14], universe)
+		}
+		if l := binary.BigEndian.Uint16(pkt[16:18]); int(l) != length {
+			t.Errorf("packet %d: length = %d, want %d", i, l, length)
+		}
+		if length > chunk && pkt[len(pkt)-1] != 0 {
+			t.Errorf("packet %d: padding = %d, want 0", i, pkt[len(pkt)-1])
+		}
+		data = append(data, pkt[artnetHeaderLen:artnetHeaderLen+chunk]...)
+	}
+
+	if !bytes.Equal(data, frame.Pixels) {
+		t.Error("pixel data doesn't match the frame")
+	}
+}
+
+func TestMappingOrder(t *testing.T) {
+	// A 3x2 matrix, pixels numbered
+	//   0 1 2
+	//   3 4 5
+	tests := []struct {
+		mapping Mapping
+		want    []int
+	}{
+		{Mapping{}, []int{0, 1, 2, 3, 4, 5}},
+		{Mapping{Serpentine: true}, []int{0, 1, 2, 5, 4, 3}},
+		{Mapping{Origin: OriginTopRight}, []int{2, 1, 0, 5, 4, 3}},
+		{Mapping{Origin: OriginBottomLeft}, []int{3, 4, 5, 0, 1, 2}},
+		{Mapping{Origin: OriginBottomRight, Serpentine: true}, []int{5, 4, 3, 0, 1, 2}},
+		{Mapping{Vertical: true}, []int{0, 3, 1, 4, 2, 5}},
+		{Mapping{Vertical: true, Serpentine: true}, []int{0, 3, 4, 1, 2, 5}},
+		{Mapping{Vertical: true, Serpentine: true, Origin: OriginTopRight}, []int{2, 5, 4, 1, 0, 3}},
+	}
+
+	for _, tt := range tests {
+		if got := tt.mapping.order(3, 2); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v: order = %v, want %v", tt.mapping, got, tt.want)
+		}
+	}
+}
+
+func TestMappedOutput(t *testing.T) {
+	conn, target := listen(t, ProtocolDDP)
+	target.Mapping = Mapping{Origin: OriginBottomLeft, Serpentine: true}
+
+	// 2x2 pixels with only a red value, sent at half brightness
+	frame := Frame{
+		Pixels:     []byte{10, 0, 0, 20, 0, 0, 30, 0, 0, 40, 0, 0},
+		Width:      2,
+		Height:     2,
+		FrameCount: 1,
+		Brightness: 50,
+	}
+	stream(t, target, frame)
+
+	// Wire order starts bottom left, then snakes back along the top row
+	pkt := readPackets(t, conn, 1)[0]
+	want := []byte{15, 0, 0, 20, 0, 0, 10, 0, 0, 5, 0, 0}
+	if got := pkt[ddpHeaderLen:]; !bytes.Equal(got, want) {
+		t.Errorf("pixels = %v, want %v", got, want)
+	}
+}
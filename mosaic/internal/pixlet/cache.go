@@ -0,0 +1,207 @@
+package pixlet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores rendered frames so displays with identical settings share one
+// render. A frame is reused until its MaxAge expires; apps that don't set
+// MaxAge use the cache's default TTL. Concurrent renders of the same key are
+// collapsed into one.
+type Cache struct {
+	mu         sync.Mutex
+	entries    map[string]*cacheEntry
+	defaultTTL time.Duration
+
+	hits   uint64
+	misses uint64
+	shared uint64
+}
+
+type cacheEntry struct {
+	frame   *Frame
+	err     error
+	expires time.Time
+	done    chan struct{} // closed when the render finishes
+}
+
+// CacheStats reports how well the cache is doing
+type CacheStats struct {
+	Hits       uint64 `json:"hits"`
+	Misses     uint64 `json:"misses"`
+	Shared     uint64 `json:"shared"` // waited on another display's in-flight render
+	Entries    int    `json:"entries"`
+	DefaultTTL int    `json:"default_ttl_secs"`
+}
+
+// NewCache creates a render cache. defaultTTL applies to apps without a
+// MaxAge; zero means such frames are only shared while rendering.
+func NewCache(defaultTTL time.Duration) *Cache {
+	return &Cache{
+		entries:    make(map[string]*cacheEntry),
+		defaultTTL: defaultTTL,
+	}
+}
+
+// Stats returns hit/miss counters
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	entries := 0
+	for _, e := range c.entries {
+		if e.expires.IsZero() || now.Before(e.expires) {
+			entries++
+		}
+	}
+
+	return CacheStats{
+		Hits:       c.hits,
+		Misses:     c.misses,
+		Shared:     c.shared,
+		Entries:    entries,
+		DefaultTTL: int(c.defaultTTL / time.Second),
+	}
+}
+
+// Clear drops all cached frames
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, e := range c.entries {
+		// Keep in-flight renders so waiters still get their result
+		if !e.expires.IsZero() {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// get returns a cached frame for key or renders it. Waiting on another
+// caller's in-flight render stops when ctx is done.
+func (c *Cache) get(ctx context.Context, key string, render func(context.Context) (*Frame, error)) (*Frame, error) {
+	for {
+		c.mu.Lock()
+		if e, ok := c.entries[key]; ok {
+			if e.expires.IsZero() {
+				// Another display is rendering this right now
+				c.shared++
+				c.mu.Unlock()
+
+				select {
+				case <-e.done:
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+
+				// The render we waited on was cancelled by its owner, not
+				// by us, so render it ourselves
+				if isCancellation(e.err) && ctx.Err() == nil {
+					continue
+				}
+				return e.frame, e.err
+			}
+			if time.Now().Before(e.expires) {
+				c.hits++
+				c.mu.Unlock()
+				return e.frame, nil
+			}
+			delete(c.entries, key)
+		}
+
+		e := &cacheEntry{done: make(chan struct{})}
+		c.entries[key] = e
+		c.misses++
+		c.mu.Unlock()
+
+		e.frame, e.err = render(ctx)
+
+		c.mu.Lock()
+		ttl := c.defaultTTL
+		if e.err == nil && e.frame.MaxAge > 0 {
+			ttl = time.Duration(e.frame.MaxAge) * time.Second
+		}
+		if e.err != nil || ttl <= 0 {
+			delete(c.entries, key)
+		} else {
+			e.expires = time.Now().Add(ttl)
+		}
+		c.mu.Unlock()
+		close(e.done)
+
+		return e.frame, e.err
+	}
+}
+
+func isCancellation(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
+
+// cacheKey identifies a render by app, config and output size. A stamp of the
+// app's files is part of it, so frames of an app stop being reused once its
+// source is updated, rolled back or replaced.
+func cacheKey(appPath string, config map[string]string, width, height int) string {
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s|%dx%d", appPath, width, height)
+	if stamp := appStamp(appPath); stamp != "" {
+		b.WriteString("|" + stamp)
+	}
+	for _, k := range keys {
+		fmt.Fprintf(&b, "|%q=%q", k, config[k])
+	}
+	return b.String()
+}
+
+// appStamp sums up the files an app is loaded from: their newest modification
+// time, count and total size. An app with modules or assets is loaded from its
+// whole directory, so a change to any file in it counts.
+func appStamp(appPath string) string {
+	info, err := os.Stat(appPath)
+	if err != nil {
+		return ""
+	}
+	latest, count, size := info.ModTime(), 1, info.Size()
+
+	dir := filepath.Dir(appPath)
+	if hasAppFiles(dir, info.Name()) {
+		latest, count, size = time.Time{}, 0, 0
+		filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if rel, _ := filepath.Rel(dir, file); rel == "app.json" || rel == "config.json" {
+				return nil
+			}
+			fi, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			// Directories change when files are added, removed or renamed
+			if fi.ModTime().After(latest) {
+				latest = fi.ModTime()
+			}
+			if !d.IsDir() {
+				count++
+				size += fi.Size()
+			}
+			return nil
+		})
+	}
+	return fmt.Sprintf("%d/%d/%d", latest.UnixNano(), count, size)
+}
@@ -0,0 +1,61 @@
+package pixlet
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Pool limits how many applets render at the same time across all displays
+type Pool struct {
+	slots   chan struct{}
+	waiting atomic.Int64
+}
+
+// PoolStats reports render pool usage
+type PoolStats struct {
+	Workers int `json:"workers"`
+	Active  int `json:"active"`
+	Waiting int `json:"waiting"`
+}
+
+// NewPool creates a pool that runs at most size renders at once
+func NewPool(size int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	return &Pool{slots: make(chan struct{}, size)}
+}
+
+// Stats returns current pool usage
+func (p *Pool) Stats() PoolStats {
+	return PoolStats{
+		Workers: cap(p.slots),
+		Active:  len(p.slots),
+		Waiting: int(p.waiting.Load()),
+	}
+}
+
+// acquire waits for a free slot. A nil pool never blocks.
+func (p *Pool) acquire(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+
+	p.waiting.Add(1)
+	defer p.waiting.Add(-1)
+
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot taken by acquire
+func (p *Pool) release() {
+	if p == nil {
+		return
+	}
+	<-p.slots
+}
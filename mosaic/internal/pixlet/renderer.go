@@ -2,10 +2,13 @@ package pixlet
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"image"
+	"image/color"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"tidbyt.dev/pixlet/render"
@@ -22,117 +25,204 @@ type Frame struct {
 	Height   int
 }
 
+// ErrNoRoots is returned when an applet renders nothing, which is how
+// applets say they have nothing to show right now
+var ErrNoRoots = errors.New("applet returned no roots")
+
+// Pixlet paints every root onto a fixed 64x32 canvas
+const (
+	baseWidth  = 64
+	baseHeight = 32
+)
+
 // Renderer handles Pixlet app rendering
 type Renderer struct {
-	width  int
-	height int
+	width   int
+	height  int
 	timeout time.Duration
+
+	// Canvas the applet lays out in, and the integer factor it is scaled
+	// up by to fill the display
+	canvasWidth  int
+	canvasHeight int
+	scale        int
+
+	// Optional cache and worker pool shared with other renderers
+	cache *Cache
+	pool  *Pool
 }
 
 // NewRenderer creates a new Pixlet renderer
 func NewRenderer(width, height int) *Renderer {
-	return &Renderer{
+	r := &Renderer{
 		width:   width,
 		height:  height,
 		timeout: 30 * time.Second,
+		scale:   1,
+	}
+
+	// Displays larger than Pixlet's canvas get a proportionally smaller
+	// canvas that is scaled up, like a 2x Tidbyt
+	for r.width/r.scale > baseWidth || r.height/r.scale > baseHeight {
+		r.scale++
 	}
+	r.canvasWidth = r.width / r.scale
+	r.canvasHeight = r.height / r.scale
+
+	return r
+}
+
+// CanvasSize returns the size applets should lay out for. It matches the
+// display unless the display is larger than Pixlet's 64x32 canvas.
+func (r *Renderer) CanvasSize() (width, height int) {
+	return r.canvasWidth, r.canvasHeight
+}
+
+// SetCache makes the renderer reuse frames from a shared cache
+func (r *Renderer) SetCache(c *Cache) {
+	r.cache = c
+}
+
+// SetPool limits the renderer to the slots of a shared worker pool
+func (r *Renderer) SetPool(p *Pool) {
+	r.pool = p
 }
 
 // RenderApp renders a .star app file with the given config
 func (r *Renderer) RenderApp(appPath string, config map[string]string) (*Frame, error) {
+	return r.RenderAppContext(context.Background(), appPath, config)
+}
+
+// RenderAppContext renders a .star app file, reusing a cached frame when one
+// is still fresh. The render is abandoned when ctx is cancelled.
+func (r *Renderer) RenderAppContext(ctx context.Context, appPath string, config map[string]string) (*Frame, error) {
+	if r.cache == nil {
+		return r.renderApp(ctx, appPath, config)
+	}
+
+	key := cacheKey(appPath, config, r.width, r.height)
+	return r.cache.get(ctx, key, func(ctx context.Context) (*Frame, error) {
+		return r.renderApp(ctx, appPath, config)
+	})
+}
+
+func (r *Renderer) renderApp(ctx context.Context, appPath string, config map[string]string) (*Frame, error) {
+	return r.render(ctx, appIDFromPath(appPath), config, func() (*runtime.Applet, error) {
+		return loadApplet(appPath)
+	})
+}
+
+// loadApplet loads a .star app file. An app that comes with other Starlark
+// modules or assets in its directory is loaded with the directory as its
+// filesystem, so relative load()s and file reads work.
+func loadApplet(appPath string) (*runtime.Applet, error) {
+	appID := appIDFromPath(appPath)
+
+	dir := filepath.Dir(appPath)
+	if hasAppFiles(dir, filepath.Base(appPath)) {
+		applet, err := runtime.NewAppletFromFS(appID, os.DirFS(dir))
+		if err != nil {
+			return nil, fmt.Errorf("creating applet: %w", err)
+		}
+		return applet, nil
+	}
+
 	// Read the app source
 	src, err := os.ReadFile(appPath)
 	if err != nil {
 		return nil, fmt.Errorf("reading app file: %w", err)
 	}
 
-	// Get app ID from filename
-	appID := filepath.Base(appPath)
-	if ext := filepath.Ext(appID); ext != "" {
-		appID = appID[:len(appID)-len(ext)]
-	}
-
-	// Create the applet
 	applet, err := runtime.NewApplet(appID, src)
 	if err != nil {
 		return nil, fmt.Errorf("creating applet: %w", err)
 	}
+	return applet, nil
+}
 
-	// Run with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
-	defer cancel()
-
-	roots, err := applet.RunWithConfig(ctx, config)
+// hasAppFiles reports whether an app directory holds files for the app
+// besides its main file and Mosaic's own metadata
+func hasAppFiles(dir, mainFile string) bool {
+	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return nil, fmt.Errorf("running applet: %w", err)
+		return false
 	}
-
-	if len(roots) == 0 {
-		return nil, fmt.Errorf("applet returned no roots")
-	}
-
-	// Paint the roots to images
-	images := render.PaintRoots(true, roots...)
-
-	// Get delay from first root
-	delayMs := 50
-	maxAge := 0
-	if len(roots) > 0 {
-		if roots[0].Delay > 0 {
-			delayMs = int(roots[0].Delay)
-		}
-		if roots[0].MaxAge > 0 {
-			maxAge = int(roots[0].MaxAge)
+	for _, e := range entries {
+		switch e.Name() {
+		case mainFile, "app.json", "config.json":
+		default:
+			return true
 		}
 	}
+	return false
+}
 
-	frame := &Frame{
-		Images:  images,
-		DelayMs: delayMs,
-		MaxAge:  maxAge,
-		AppName: appID,
-		Width:   r.width,
-		Height:  r.height,
-	}
-
-	// Default delay if not set
-	if frame.DelayMs == 0 {
-		frame.DelayMs = 50
+// appIDFromPath returns the app ID for a .star file: its name without the
+// extension
+func appIDFromPath(appPath string) string {
+	appID := filepath.Base(appPath)
+	if ext := filepath.Ext(appID); ext != "" {
+		appID = appID[:len(appID)-len(ext)]
 	}
-
-	return frame, nil
+	return appID
 }
 
 // RenderAppFromSource renders a .star app from source code
 func (r *Renderer) RenderAppFromSource(appID string, src []byte, config map[string]string) (*Frame, error) {
-	applet, err := runtime.NewApplet(appID, src)
+	return r.RenderSourceContext(context.Background(), appID, src, config)
+}
+
+// RenderSourceContext renders a .star app from source code once a pool slot
+// is free. The render is abandoned when ctx is cancelled.
+func (r *Renderer) RenderSourceContext(ctx context.Context, appID string, src []byte, config map[string]string) (*Frame, error) {
+	return r.render(ctx, appID, config, func() (*runtime.Applet, error) {
+		applet, err := runtime.NewApplet(appID, src)
+		if err != nil {
+			return nil, fmt.Errorf("creating applet: %w", err)
+		}
+		return applet, nil
+	})
+}
+
+// render loads an applet once a pool slot is free and runs it
+func (r *Renderer) render(ctx context.Context, appID string, config map[string]string, load func() (*runtime.Applet, error)) (*Frame, error) {
+	if err := r.pool.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer r.pool.release()
+
+	applet, err := load()
 	if err != nil {
-		return nil, fmt.Errorf("creating applet: %w", err)
+		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
 
-	roots, err := applet.RunWithConfig(ctx, config)
+	roots, err := applet.RunWithConfig(ctx, r.withCanvasConfig(config))
 	if err != nil {
+		// Surface cancellation and timeouts as context errors
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("running applet: %w", ctxErr)
+		}
 		return nil, fmt.Errorf("running applet: %w", err)
 	}
 
 	if len(roots) == 0 {
-		return nil, fmt.Errorf("applet returned no roots")
+		return nil, ErrNoRoots
 	}
 
-	images := render.PaintRoots(true, roots...)
+	// Paint the roots to images and fit them to the display
+	images := r.fitImages(render.PaintRoots(true, roots...))
 
+	// Get delay from first root
 	delayMs := 50
 	maxAge := 0
-	if len(roots) > 0 {
-		if roots[0].Delay > 0 {
-			delayMs = int(roots[0].Delay)
-		}
-		if roots[0].MaxAge > 0 {
-			maxAge = int(roots[0].MaxAge)
-		}
+	if roots[0].Delay > 0 {
+		delayMs = int(roots[0].Delay)
+	}
+	if roots[0].MaxAge > 0 {
+		maxAge = int(roots[0].MaxAge)
 	}
 
 	frame := &Frame{
@@ -147,6 +237,41 @@ func (r *Renderer) RenderAppFromSource(appID string, src []byte, config map[stri
 	return frame, nil
 }
 
+// withCanvasConfig returns a copy of config with the canvas size set as
+// $width and $height, so applets can lay out for the display
+func (r *Renderer) withCanvasConfig(config map[string]string) map[string]string {
+	result := make(map[string]string, len(config)+2)
+	for k, v := range config {
+		result[k] = v
+	}
+	result["$width"] = strconv.Itoa(r.canvasWidth)
+	result["$height"] = strconv.Itoa(r.canvasHeight)
+	return result
+}
+
+// fitImages crops painted images to the canvas and scales them up to the
+// display size, so the pixel buffer always matches the display
+func (r *Renderer) fitImages(images []image.Image) []image.Image {
+	result := make([]image.Image, len(images))
+	for i, img := range images {
+		out := image.NewRGBA(image.Rect(0, 0, r.width, r.height))
+		bounds := img.Bounds()
+
+		for y := 0; y < r.canvasHeight && y < bounds.Dy(); y++ {
+			for x := 0; x < r.canvasWidth && x < bounds.Dx(); x++ {
+				c := color.RGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.RGBA)
+				for dy := 0; dy < r.scale; dy++ {
+					for dx := 0; dx < r.scale; dx++ {
+						out.SetRGBA(x*r.scale+dx, y*r.scale+dy, c)
+					}
+				}
+			}
+		}
+		result[i] = out
+	}
+	return result
+}
+
 // ImagesToRGB converts images to raw RGB bytes
 func ImagesToRGB(images []image.Image) []byte {
 	if len(images) == 0 {
@@ -183,19 +308,9 @@ func ImagesToRGB(images []image.Image) []byte {
 
 // GetSchema extracts the schema from a .star app
 func (r *Renderer) GetSchema(appPath string) ([]byte, error) {
-	src, err := os.ReadFile(appPath)
+	applet, err := loadApplet(appPath)
 	if err != nil {
-		return nil, fmt.Errorf("reading app file: %w", err)
-	}
-
-	appID := filepath.Base(appPath)
-	if ext := filepath.Ext(appID); ext != "" {
-		appID = appID[:len(appID)-len(ext)]
-	}
-
-	applet, err := runtime.NewApplet(appID, src)
-	if err != nil {
-		return nil, fmt.Errorf("creating applet: %w", err)
+		return nil, err
 	}
 
 	return applet.SchemaJSON, nil
@@ -0,0 +1,23 @@
+package rotation
+
+import "fmt"
+
+// FailurePolicy says what a display does when an app fails to render
+type FailurePolicy string
+
+const (
+	FailureShowError FailurePolicy = "error"     // show the error screen (default)
+	FailureLastGood  FailurePolicy = "last_good" // keep showing the app's last good frame
+	FailureRetry     FailurePolicy = "retry"     // retry with backoff while the app is up
+	FailureSkip      FailurePolicy = "skip"      // move on to the next app
+)
+
+// Validate checks the policy is a known one; empty is allowed and means the
+// default
+func (p FailurePolicy) Validate() error {
+	switch p {
+	case "", FailureShowError, FailureLastGood, FailureRetry, FailureSkip:
+		return nil
+	}
+	return fmt.Errorf("unknown failure policy %q (use error, last_good, retry or skip)", p)
+}
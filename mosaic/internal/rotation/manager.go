@@ -1,6 +1,7 @@
 package rotation
 
 import (
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -14,6 +15,37 @@ type AppEntry struct {
 	Config   map[string]string `json:"config" yaml:"config"`
 	DwellMs  int               `json:"dwell_ms" yaml:"dwell_ms"`   // 0 = use default
 	Enabled  bool              `json:"enabled" yaml:"enabled"`
+	Schedule *Schedule         `json:"schedule,omitempty" yaml:"schedule,omitempty"` // nil = always
+	Weight   int               `json:"weight,omitempty" yaml:"weight,omitempty"`     // weighted strategy; 0 = 1
+	Every    int               `json:"every,omitempty" yaml:"every,omitempty"`       // frequency strategy; show every N slots
+
+	// What to do when the app fails to render; empty = the global policy
+	OnFailure   FailurePolicy `json:"on_failure,omitempty" yaml:"on_failure,omitempty"`
+	MaxFailures int           `json:"max_failures,omitempty" yaml:"max_failures,omitempty"` // disable after this many in a row; 0 = global
+}
+
+// Validate checks the entry's schedule, strategy and failure settings
+func (a AppEntry) Validate() error {
+	if a.Weight < 0 {
+		return fmt.Errorf("weight must not be negative")
+	}
+	if a.Every < 0 {
+		return fmt.Errorf("every must not be negative")
+	}
+	if a.MaxFailures < 0 {
+		return fmt.Errorf("max_failures must not be negative")
+	}
+	if err := a.OnFailure.Validate(); err != nil {
+		return err
+	}
+	return a.Schedule.Validate()
+}
+
+func (a AppEntry) weight() int {
+	if a.Weight == 0 {
+		return 1
+	}
+	return a.Weight
 }
 
 // Manager handles app rotation for a display
@@ -25,29 +57,53 @@ type Manager struct {
 	enabled      bool
 	defaultDwell time.Duration
 	brightness   int
-
-	// Notification queue (higher priority)
-	notifyQueue []Notification
+	location     *time.Location // for schedules; nil = server local time
+	idle         bool           // no app is currently scheduled
+	strategy     Strategy       // picks the next app
+	upcoming     int            // next app already picked by the strategy; -1 = not yet
+	committed    bool           // the strategy has recorded the app at currentIndex
+
+	// Notification queue (higher priority), ordered by priority then age.
+	// The head of the queue is what should be on screen.
+	notifyQueue  []Notification
+	showing      string    // ID of the notification on screen, if any
+	showingSince time.Time // when it went on screen
+	refresh      bool      // the notification on screen was changed
 
 	// Channels for control
-	skipCh   chan struct{}
-	updateCh chan struct{}
-	stopCh   chan struct{}
+	skipCh    chan struct{}
+	updateCh  chan struct{}
+	restartCh chan struct{}
+	emptyCh   chan string
+	stopCh    chan struct{}
 
 	// Callback when rotation advances
 	onAdvance func(app AppEntry)
+
+	// Callback when no app is scheduled to show
+	onIdle func()
+
+	// Callback when a notification takes over the display
+	onNotify func(n Notification)
+
+	// Callback when notifications end while rotation is off
+	onRestore func()
 }
 
 // Notification represents a temporary display override
 type Notification struct {
 	ID       string            `json:"id"`
 	Text     string            `json:"text,omitempty"`
+	Color    string            `json:"color,omitempty"`
 	AppID    string            `json:"app_id,omitempty"`
 	Source   string            `json:"source,omitempty"`
 	Config   map[string]string `json:"config,omitempty"`
 	Duration time.Duration     `json:"duration"`
 	Priority Priority          `json:"priority"`
 	Created  time.Time         `json:"created"`
+
+	// Time already spent on screen before being interrupted
+	shown time.Duration
 }
 
 // Priority levels for notifications
@@ -71,8 +127,12 @@ func NewManager(defaultDwell time.Duration) *Manager {
 		enabled:      true,
 		defaultDwell: defaultDwell,
 		brightness:   80,
+		strategy:     &sequential{},
+		upcoming:     -1,
 		skipCh:       make(chan struct{}, 1),
 		updateCh:     make(chan struct{}, 1),
+		restartCh:    make(chan struct{}, 1),
+		emptyCh:      make(chan string, 1),
 		stopCh:       make(chan struct{}),
 	}
 }
@@ -83,6 +143,7 @@ func (m *Manager) SetApps(apps []AppEntry) {
 	defer m.mu.Unlock()
 
 	m.apps = apps
+	m.upcoming = -1
 	if m.currentIndex >= len(apps) {
 		m.currentIndex = 0
 	}
@@ -90,6 +151,23 @@ func (m *Manager) SetApps(apps []AppEntry) {
 	m.notifyUpdate()
 }
 
+// Restart replaces the app list and shows its first app right away. A
+// notification on screen stays up; the new list starts when it ends.
+func (m *Manager) Restart(apps []AppEntry) {
+	m.mu.Lock()
+	m.apps = apps
+	m.currentIndex = 0
+	m.upcoming = -1
+	m.committed = false
+	m.idle = false
+	m.mu.Unlock()
+
+	select {
+	case m.restartCh <- struct{}{}:
+	default:
+	}
+}
+
 // GetApps returns the current app list
 func (m *Manager) GetApps() []AppEntry {
 	m.mu.RLock()
@@ -106,6 +184,7 @@ func (m *Manager) AddApp(app AppEntry) {
 	defer m.mu.Unlock()
 
 	m.apps = append(m.apps, app)
+	m.upcoming = -1
 	m.notifyUpdate()
 }
 
@@ -117,6 +196,7 @@ func (m *Manager) RemoveApp(id string) bool {
 	for i, app := range m.apps {
 		if app.ID == id {
 			m.apps = append(m.apps[:i], m.apps[i+1:]...)
+			m.upcoming = -1
 			if m.currentIndex >= len(m.apps) && len(m.apps) > 0 {
 				m.currentIndex = 0
 			}
@@ -127,6 +207,56 @@ func (m *Manager) RemoveApp(id string) bool {
 	return false
 }
 
+// UpdateApp modifies the rotation entry with the given ID
+func (m *Manager) UpdateApp(id string, fn func(app *AppEntry)) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.apps {
+		if m.apps[i].ID == id {
+			fn(&m.apps[i])
+			m.upcoming = -1
+			m.notifyUpdate()
+			return true
+		}
+	}
+	return false
+}
+
+// SetLocation sets the timezone schedules are evaluated in
+func (m *Manager) SetLocation(loc *time.Location) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.location = loc
+}
+
+// SetStrategy sets how the rotation picks the next app
+func (m *Manager) SetStrategy(strategy Strategy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.strategy = strategy
+	m.upcoming = -1
+	if m.committed && m.currentIndex < len(m.apps) {
+		// Carry on from the app on screen
+		strategy.Commit(m.apps, m.currentIndex)
+	}
+}
+
+// Strategy returns the name of the rotation strategy
+func (m *Manager) Strategy() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.strategy.Name()
+}
+
+// IsScheduled reports whether an entry is enabled and its schedule allows
+// showing it right now
+func (m *Manager) IsScheduled(app AppEntry) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.scheduledLocked(app, m.nowLocked())
+}
+
 // SetEnabled enables or disables rotation
 func (m *Manager) SetEnabled(enabled bool) {
 	m.mu.Lock()
@@ -169,6 +299,16 @@ func (m *Manager) Skip() {
 	}
 }
 
+// SkipEmpty reports that an app rendered nothing to show. If it is the app
+// on screen the rotation moves on right away; once every scheduled app has
+// come up empty in a row it goes idle for a dwell instead.
+func (m *Manager) SkipEmpty(appID string) {
+	select {
+	case m.emptyCh <- appID:
+	default:
+	}
+}
+
 // CurrentApp returns the currently displayed app
 func (m *Manager) CurrentApp() *AppEntry {
 	m.mu.RLock()
@@ -178,10 +318,11 @@ func (m *Manager) CurrentApp() *AppEntry {
 		return nil
 	}
 
-	// Find next enabled app
+	// Find next enabled app that is scheduled now
+	now := m.nowLocked()
 	for i := 0; i < len(m.apps); i++ {
 		idx := (m.currentIndex + i) % len(m.apps)
-		if m.apps[idx].Enabled {
+		if m.scheduledLocked(m.apps[idx], now) {
 			app := m.apps[idx]
 			return &app
 		}
@@ -190,13 +331,53 @@ func (m *Manager) CurrentApp() *AppEntry {
 	return nil
 }
 
+// FirstApp returns the app the rotation starts with, like CurrentApp, and
+// records it with the strategy so the rotation carries on from it
+func (m *Manager) FirstApp() *AppEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.nowLocked()
+	for i := 0; i < len(m.apps); i++ {
+		idx := (m.currentIndex + i) % len(m.apps)
+		if m.scheduledLocked(m.apps[idx], now) {
+			if !m.committed || idx != m.currentIndex {
+				m.commitLocked(idx)
+			}
+			app := m.apps[idx]
+			return &app
+		}
+	}
+	return nil
+}
+
+// NextApp returns the app the rotation will advance to next
+func (m *Manager) NextApp() *AppEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.apps) == 0 {
+		return nil
+	}
+
+	idx, ok := m.nextIndexLocked(m.nowLocked())
+	if !ok {
+		return nil
+	}
+	app := m.apps[idx]
+	return &app
+}
+
 // PushNotification adds a notification to the queue
 func (m *Manager) PushNotification(n Notification) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	n.Created = time.Now()
-	
+	if n.Duration <= 0 && n.Priority != PrioritySticky {
+		n.Duration = m.defaultDwell
+	}
+
 	// Insert by priority (higher priority first)
 	inserted := false
 	for i, existing := range m.notifyQueue {
@@ -225,15 +406,42 @@ func (m *Manager) ClearNotifications() {
 		}
 	}
 	m.notifyQueue = sticky
+	m.notifyUpdate()
 }
 
-// OnAdvance sets the callback for when rotation advances
+// OnAdvance sets the callback for when rotation advances. The callback runs
+// on the rotation goroutine, so it must hand slow work off and return quickly.
 func (m *Manager) OnAdvance(fn func(app AppEntry)) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.onAdvance = fn
 }
 
+// OnIdle sets the callback for when rotation finds no app scheduled to show.
+// It is called once each time rotation goes idle, on the rotation goroutine.
+func (m *Manager) OnIdle(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onIdle = fn
+}
+
+// OnNotify sets the callback for when a notification takes over the
+// display. Like OnAdvance it runs on the rotation goroutine.
+func (m *Manager) OnNotify(fn func(n Notification)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onNotify = fn
+}
+
+// OnRestore sets the callback for when notifications end while rotation is
+// off, to put back what was showing before. Like OnAdvance it runs on the
+// rotation goroutine.
+func (m *Manager) OnRestore(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onRestore = fn
+}
+
 // Run starts the rotation loop (blocking)
 func (m *Manager) Run() {
 	ticker := time.NewTicker(100 * time.Millisecond)
@@ -241,6 +449,31 @@ func (m *Manager) Run() {
 
 	var lastAdvance time.Time
 	var currentDwell time.Duration
+	var pausedAt time.Time // when notifications interrupted the rotation
+	var emptyStreak int    // apps in a row that had nothing to show
+
+	// checkNotifications puts the right notification on screen, or resumes
+	// the rotation once the queue is empty. It reports whether a
+	// notification is showing.
+	checkNotifications := func() bool {
+		now := time.Now()
+		n, resume := m.updateNotifications(now)
+		if n != nil {
+			if pausedAt.IsZero() {
+				pausedAt = now
+			}
+			m.showNotification(*n)
+			return true
+		}
+		if resume {
+			// Carry on with the interrupted app's remaining dwell
+			lastAdvance = lastAdvance.Add(now.Sub(pausedAt))
+			pausedAt = time.Time{}
+			m.resume()
+			return false
+		}
+		return !pausedAt.IsZero()
+	}
 
 	for {
 		select {
@@ -248,16 +481,50 @@ func (m *Manager) Run() {
 			return
 
 		case <-m.skipCh:
+			// Skipping a notification dismisses it
+			if m.dismissShowing() {
+				checkNotifications()
+				continue
+			}
+			emptyStreak = 0
 			m.advance()
 			lastAdvance = time.Now()
 			currentDwell = m.getCurrentDwell()
 
+		case id := <-m.emptyCh:
+			if !m.IsEnabled() || !pausedAt.IsZero() || !m.isCurrent(id) {
+				continue
+			}
+			emptyStreak++
+			if emptyStreak >= m.scheduledCount() {
+				// Everything is empty; try again after a dwell
+				emptyStreak = 0
+				m.setIdle("every scheduled app is empty")
+			} else {
+				m.advance()
+			}
+			lastAdvance = time.Now()
+			currentDwell = m.getCurrentDwell()
+
+		case <-m.restartCh:
+			// While a notification shows, resume() picks up the new list
+			// once it ends
+			if !m.IsEnabled() || !pausedAt.IsZero() {
+				continue
+			}
+			emptyStreak = 0
+			m.resume()
+			lastAdvance = time.Now()
+			currentDwell = m.getCurrentDwell()
+
 		case <-m.updateCh:
 			// Config changed, recalculate
 			currentDwell = m.getCurrentDwell()
+			checkNotifications()
 
 		case <-ticker.C:
-			if !m.IsEnabled() {
+			// Notifications show whether or not the rotation is on
+			if checkNotifications() || !m.IsEnabled() {
 				continue
 			}
 
@@ -268,6 +535,7 @@ func (m *Manager) Run() {
 			}
 
 			if time.Since(lastAdvance) >= currentDwell {
+				emptyStreak = 0
 				m.advance()
 				lastAdvance = time.Now()
 				currentDwell = m.getCurrentDwell()
@@ -283,34 +551,242 @@ func (m *Manager) Stop() {
 
 func (m *Manager) advance() {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 
 	if len(m.apps) == 0 {
+		m.mu.Unlock()
 		return
 	}
 
-	// Find next enabled app
-	startIdx := m.currentIndex
-	for i := 0; i < len(m.apps); i++ {
-		m.currentIndex = (startIdx + i + 1) % len(m.apps)
-		if m.apps[m.currentIndex].Enabled {
-			break
+	idx, ok := m.nextIndexLocked(m.nowLocked())
+	if !ok {
+		m.mu.Unlock()
+		m.setIdle("no app scheduled")
+		return
+	}
+
+	m.commitLocked(idx)
+	m.idle = false
+	app := m.apps[m.currentIndex]
+	onAdvance := m.onAdvance
+	m.mu.Unlock()
+
+	log.Printf("Rotation advanced to: %s", app.Name)
+
+	// Called in order on the rotation goroutine so the receiver can tell
+	// newer advances from older ones
+	if onAdvance != nil {
+		onAdvance(app)
+	}
+}
+
+// commitLocked moves the rotation on to apps[idx]. Caller must hold m.mu
+// for writing.
+func (m *Manager) commitLocked(idx int) {
+	m.strategy.Commit(m.apps, idx)
+	m.currentIndex = idx
+	m.upcoming = -1
+	m.committed = true
+}
+
+// setIdle stops showing apps until the next advance, calling onIdle if the
+// rotation wasn't idle already
+func (m *Manager) setIdle(reason string) {
+	m.mu.Lock()
+	wasIdle := m.idle
+	m.idle = true
+	onIdle := m.onIdle
+	m.mu.Unlock()
+
+	if !wasIdle {
+		log.Printf("Rotation idle: %s", reason)
+		if onIdle != nil {
+			onIdle()
 		}
 	}
+}
+
+// isCurrent reports whether the app with the given ID is the one on screen
+func (m *Manager) isCurrent(appID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return !m.idle && m.currentIndex < len(m.apps) && m.apps[m.currentIndex].ID == appID
+}
+
+// scheduledCount returns how many apps may show right now
+func (m *Manager) scheduledCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	if m.onAdvance != nil {
-		app := m.apps[m.currentIndex]
-		go m.onAdvance(app)
+	now := m.nowLocked()
+	count := 0
+	for _, app := range m.apps {
+		if m.scheduledLocked(app, now) {
+			count++
+		}
 	}
+	return count
+}
+
+// updateNotifications expires the notification on screen and picks the one
+// that should replace it. It returns the notification to show if that
+// changed, or resume=true when the last one has ended.
+func (m *Manager) updateNotifications(now time.Time) (show *Notification, resume bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	wasShowing := m.showing != ""
 
-	log.Printf("Rotation advanced to: %s", m.apps[m.currentIndex].Name)
+	// End the current notification once its time is up; sticky ones stay
+	// until they are dismissed
+	if idx := m.notificationIndexLocked(m.showing); idx >= 0 {
+		n := m.notifyQueue[idx]
+		if n.Priority != PrioritySticky && n.shown+now.Sub(m.showingSince) >= n.Duration {
+			m.notifyQueue = append(m.notifyQueue[:idx], m.notifyQueue[idx+1:]...)
+			m.showing = ""
+		}
+	} else {
+		// Dismissed while on screen
+		m.showing = ""
+	}
+
+	if len(m.notifyQueue) == 0 {
+		return nil, wasShowing
+	}
+
+	head := m.notifyQueue[0]
+	if head.ID == m.showing {
+		if m.refresh {
+			// Same notification, new content
+			m.refresh = false
+			return &head, false
+		}
+		return nil, false
+	}
+
+	// A higher priority notification interrupts the current one, which
+	// keeps its remaining time for later
+	if idx := m.notificationIndexLocked(m.showing); idx >= 0 {
+		m.notifyQueue[idx].shown += now.Sub(m.showingSince)
+	}
+	m.showing = head.ID
+	m.showingSince = now
+	m.refresh = false
+	return &head, false
+}
+
+// dismissShowing removes the notification on screen, if any
+func (m *Manager) dismissShowing() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	idx := m.notificationIndexLocked(m.showing)
+	if idx < 0 {
+		return false
+	}
+	m.notifyQueue = append(m.notifyQueue[:idx], m.notifyQueue[idx+1:]...)
+	return true
+}
+
+func (m *Manager) notificationIndexLocked(id string) int {
+	if id == "" {
+		return -1
+	}
+	for i, n := range m.notifyQueue {
+		if n.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *Manager) showNotification(n Notification) {
+	m.mu.RLock()
+	onNotify := m.onNotify
+	m.mu.RUnlock()
+
+	log.Printf("Showing notification: %s", n.ID)
+	if onNotify != nil {
+		onNotify(n)
+	}
+}
+
+// resume shows the app that was on screen before notifications took over
+func (m *Manager) resume() {
+	m.mu.Lock()
+	if !m.enabled {
+		onRestore := m.onRestore
+		m.mu.Unlock()
+		if onRestore != nil {
+			onRestore()
+		}
+		return
+	}
+	var app *AppEntry
+	idle := m.idle || len(m.apps) == 0
+	if m.currentIndex < len(m.apps) && m.scheduledLocked(m.apps[m.currentIndex], m.nowLocked()) {
+		entry := m.apps[m.currentIndex]
+		app = &entry
+		if !idle && !m.committed {
+			// First app of a restarted list
+			m.commitLocked(m.currentIndex)
+		}
+	}
+	onAdvance, onIdle := m.onAdvance, m.onIdle
+	m.mu.Unlock()
+
+	switch {
+	case app != nil && !idle:
+		log.Printf("Rotation resumed: %s", app.Name)
+		if onAdvance != nil {
+			onAdvance(*app)
+		}
+	case idle:
+		if onIdle != nil {
+			onIdle()
+		}
+	default:
+		// The interrupted app's schedule ended in the meantime
+		m.advance()
+	}
+}
+
+// nextIndexLocked returns the index of the app the strategy picks to follow
+// the current one among those enabled and scheduled at now. The pick is kept
+// until the rotation advances, so NextApp and advance agree. Caller must
+// hold m.mu for writing.
+func (m *Manager) nextIndexLocked(now time.Time) (int, bool) {
+	if m.upcoming >= 0 && m.upcoming < len(m.apps) && m.scheduledLocked(m.apps[m.upcoming], now) {
+		return m.upcoming, true
+	}
+
+	idx, ok := m.strategy.Next(m.apps, m.currentIndex, func(i int) bool {
+		return m.scheduledLocked(m.apps[i], now)
+	})
+	if !ok {
+		m.upcoming = -1
+		return 0, false
+	}
+	m.upcoming = idx
+	return idx, true
+}
+
+func (m *Manager) scheduledLocked(app AppEntry, now time.Time) bool {
+	return app.Enabled && app.Schedule.Active(now)
+}
+
+// nowLocked returns the current time in the rotation's timezone
+func (m *Manager) nowLocked() time.Time {
+	if m.location == nil {
+		return time.Now()
+	}
+	return time.Now().In(m.location)
 }
 
 func (m *Manager) getCurrentDwell() time.Duration {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	if len(m.apps) == 0 {
+	if len(m.apps) == 0 || m.idle {
 		return m.defaultDwell
 	}
 
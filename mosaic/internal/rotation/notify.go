@@ -0,0 +1,182 @@
+package rotation
+
+import (
+	"fmt"
+	"time"
+)
+
+var priorityNames = map[Priority]string{
+	PriorityLow:    "low",
+	PriorityNormal: "normal",
+	PriorityHigh:   "high",
+	PrioritySticky: "sticky",
+}
+
+// String returns the API name of the priority
+func (p Priority) String() string {
+	if name, ok := priorityNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("priority(%d)", int(p))
+}
+
+// ParsePriority parses a priority name; empty or unknown means normal
+func ParsePriority(s string) Priority {
+	for p, name := range priorityNames {
+		if name == s {
+			return p
+		}
+	}
+	return PriorityNormal
+}
+
+// NotificationStatus is a queued notification and where it stands
+type NotificationStatus struct {
+	Notification
+	Showing   bool
+	Remaining time.Duration // time left on screen; 0 for sticky
+}
+
+// NotificationUpdate changes a queued notification. Nil fields are left
+// alone.
+type NotificationUpdate struct {
+	Text      *string
+	Color     *string
+	Source    *string
+	Config    map[string]string
+	Remaining *time.Duration // new time left on screen, from now
+	Extend    time.Duration  // added to the time left
+}
+
+// GetNotifications returns the queue in the order notifications will show
+func (m *Manager) GetNotifications() []NotificationStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	result := make([]NotificationStatus, 0, len(m.notifyQueue))
+	for _, n := range m.notifyQueue {
+		result = append(result, NotificationStatus{
+			Notification: n,
+			Showing:      n.ID == m.showing,
+			Remaining:    m.remainingLocked(n, now),
+		})
+	}
+	return result
+}
+
+// GetNotification returns a queued notification by ID
+func (m *Manager) GetNotification(id string) (*NotificationStatus, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	idx := m.notificationIndexLocked(id)
+	if idx < 0 {
+		return nil, false
+	}
+	n := m.notifyQueue[idx]
+	return &NotificationStatus{
+		Notification: n,
+		Showing:      n.ID == m.showing,
+		Remaining:    m.remainingLocked(n, time.Now()),
+	}, true
+}
+
+// DismissNotification removes a notification from the queue, taking it off
+// screen if it is showing
+func (m *Manager) DismissNotification(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	idx := m.notificationIndexLocked(id)
+	if idx < 0 {
+		return false
+	}
+	m.notifyQueue = append(m.notifyQueue[:idx], m.notifyQueue[idx+1:]...)
+	m.notifyUpdate()
+	return true
+}
+
+// ClearAllNotifications clears the queue, sticky notifications included
+func (m *Manager) ClearAllNotifications() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.notifyQueue = nil
+	m.notifyUpdate()
+}
+
+// UpdateNotification changes a queued notification. If it is on screen it
+// is shown again with the new content.
+func (m *Manager) UpdateNotification(id string, update NotificationUpdate) (*NotificationStatus, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	idx := m.notificationIndexLocked(id)
+	if idx < 0 {
+		return nil, false
+	}
+
+	now := time.Now()
+	n := &m.notifyQueue[idx]
+	if update.Text != nil {
+		n.Text = *update.Text
+	}
+	if update.Color != nil {
+		n.Color = *update.Color
+	}
+	if update.Source != nil {
+		n.Source = *update.Source
+	}
+	if update.Config != nil {
+		n.Config = update.Config
+	}
+	if n.Priority != PrioritySticky {
+		if update.Remaining != nil {
+			n.Duration += *update.Remaining - m.remainingLocked(*n, now)
+		}
+		n.Duration += update.Extend
+	}
+
+	if n.ID == m.showing && (update.Text != nil || update.Color != nil || update.Source != nil) {
+		m.refresh = true
+	}
+	m.notifyUpdate()
+
+	return &NotificationStatus{
+		Notification: *n,
+		Showing:      n.ID == m.showing,
+		Remaining:    m.remainingLocked(*n, now),
+	}, true
+}
+
+// HoldNotification keeps a notification that is on screen up for at least
+// another minimum, e.g. until its animation has played through once
+func (m *Manager) HoldNotification(id string, minimum time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	idx := m.notificationIndexLocked(id)
+	if idx < 0 || id != m.showing {
+		return
+	}
+	n := &m.notifyQueue[idx]
+	if remaining := m.remainingLocked(*n, time.Now()); n.Priority != PrioritySticky && remaining < minimum {
+		n.Duration += minimum - remaining
+	}
+}
+
+// remainingLocked returns how long a notification has left on screen
+func (m *Manager) remainingLocked(n Notification, now time.Time) time.Duration {
+	if n.Priority == PrioritySticky {
+		return 0
+	}
+	remaining := n.Duration - n.shown
+	if n.ID == m.showing {
+		remaining -= now.Sub(m.showingSince)
+	}
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
@@ -0,0 +1,329 @@
+package rotation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule restricts when a rotation entry may be shown. Each kind of rule
+// that is set must match (windows AND days AND dates AND cron); within one
+// kind any entry may match. An empty schedule always matches.
+type Schedule struct {
+	Windows    []TimeWindow `json:"windows,omitempty" yaml:"windows,omitempty"`
+	Days       []string     `json:"days,omitempty" yaml:"days,omitempty"`
+	DateRanges []DateRange  `json:"date_ranges,omitempty" yaml:"date_ranges,omitempty"`
+	Cron       []string     `json:"cron,omitempty" yaml:"cron,omitempty"`
+}
+
+// TimeWindow is a time-of-day range in "HH:MM" form. A window whose end is
+// before its start runs past midnight, e.g. 22:00-06:00; one that ends where
+// it starts is empty and rejected.
+type TimeWindow struct {
+	Start string `json:"start" yaml:"start"`
+	End   string `json:"end" yaml:"end"`
+}
+
+// DateRange is an inclusive range of dates, either "YYYY-MM-DD" or "MM-DD"
+// for a range that repeats every year
+type DateRange struct {
+	Start string `json:"start" yaml:"start"`
+	End   string `json:"end" yaml:"end"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Validate checks every rule in the schedule
+func (s *Schedule) Validate() error {
+	if s == nil {
+		return nil
+	}
+
+	for _, w := range s.Windows {
+		start, err := parseClock(w.Start)
+		if err != nil {
+			return err
+		}
+		end, err := parseClock(w.End)
+		if err != nil {
+			return err
+		}
+		if start == end {
+			return fmt.Errorf("window %s-%s is empty (use 00:00-24:00 for all day)", w.Start, w.End)
+		}
+	}
+	for _, d := range s.Days {
+		if _, ok := weekdays[strings.ToLower(d)]; !ok {
+			return fmt.Errorf("invalid day %q (use sun, mon, tue, wed, thu, fri, sat)", d)
+		}
+	}
+	for _, r := range s.DateRanges {
+		start, err := parseDate(r.Start)
+		if err != nil {
+			return err
+		}
+		end, err := parseDate(r.End)
+		if err != nil {
+			return err
+		}
+		if start.yearly != end.yearly {
+			return fmt.Errorf("date range %s..%s mixes yearly and fixed dates", r.Start, r.End)
+		}
+	}
+	for _, expr := range s.Cron {
+		if _, err := parseCron(expr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Active reports whether the schedule allows showing at t. Invalid rules
+// never match.
+func (s *Schedule) Active(t time.Time) bool {
+	if s == nil {
+		return true
+	}
+
+	if len(s.Windows) > 0 && !s.matchWindows(t) {
+		return false
+	}
+	if len(s.Days) > 0 && !s.matchDays(t) {
+		return false
+	}
+	if len(s.DateRanges) > 0 && !s.matchDates(t) {
+		return false
+	}
+	if len(s.Cron) > 0 && !s.matchCron(t) {
+		return false
+	}
+	return true
+}
+
+// String summarizes the schedule for logs and the dashboard
+func (s *Schedule) String() string {
+	if s == nil {
+		return "always"
+	}
+
+	var parts []string
+	if len(s.Days) > 0 {
+		parts = append(parts, strings.Join(s.Days, ","))
+	}
+	for _, w := range s.Windows {
+		parts = append(parts, w.Start+"-"+w.End)
+	}
+	for _, r := range s.DateRanges {
+		parts = append(parts, r.Start+".."+r.End)
+	}
+	for _, c := range s.Cron {
+		parts = append(parts, "cron("+c+")")
+	}
+	if len(parts) == 0 {
+		return "always"
+	}
+	return strings.Join(parts, " ")
+}
+
+func (s *Schedule) matchWindows(t time.Time) bool {
+	now := t.Hour()*60 + t.Minute()
+	for _, w := range s.Windows {
+		start, err1 := parseClock(w.Start)
+		end, err2 := parseClock(w.End)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if start <= end {
+			if now >= start && now < end {
+				return true
+			}
+		} else if now >= start || now < end {
+			// Wraps past midnight
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schedule) matchDays(t time.Time) bool {
+	for _, d := range s.Days {
+		if wd, ok := weekdays[strings.ToLower(d)]; ok && wd == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schedule) matchDates(t time.Time) bool {
+	for _, r := range s.DateRanges {
+		start, err1 := parseDate(r.Start)
+		end, err2 := parseDate(r.End)
+		if err1 != nil || err2 != nil || start.yearly != end.yearly {
+			continue
+		}
+
+		if start.yearly {
+			day := int(t.Month())*100 + t.Day()
+			from, to := start.month*100+start.day, end.month*100+end.day
+			if from <= to {
+				if day >= from && day <= to {
+					return true
+				}
+			} else if day >= from || day <= to {
+				// Wraps past new year, e.g. 12-15..01-05
+				return true
+			}
+			continue
+		}
+
+		day := t.Year()*10000 + int(t.Month())*100 + t.Day()
+		from := start.year*10000 + start.month*100 + start.day
+		to := end.year*10000 + end.month*100 + end.day
+		if day >= from && day <= to {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schedule) matchCron(t time.Time) bool {
+	for _, expr := range s.Cron {
+		if c, err := parseCron(expr); err == nil && c.match(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock parses "HH:MM" into minutes since midnight ("24:00" is allowed
+// as the end of the day)
+func parseClock(v string) (int, error) {
+	parts := strings.Split(v, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid time %q (use HH:MM)", v)
+	}
+	h, err1 := strconv.Atoi(parts[0])
+	m, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q (use HH:MM)", v)
+	}
+	return h*60 + m, nil
+}
+
+type scheduleDate struct {
+	year, month, day int
+	yearly           bool
+}
+
+func parseDate(v string) (scheduleDate, error) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return scheduleDate{year: t.Year(), month: int(t.Month()), day: t.Day()}, nil
+	}
+	if t, err := time.Parse("01-02", v); err == nil {
+		return scheduleDate{month: int(t.Month()), day: t.Day(), yearly: true}, nil
+	}
+	return scheduleDate{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD or MM-DD)", v)
+}
+
+// cronExpr is a parsed 5-field cron expression: minute hour day-of-month
+// month day-of-week. An entry is active during every minute it matches.
+type cronExpr struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+func parseCron(expr string) (*cronExpr, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron %q: expected 5 fields", expr)
+	}
+
+	var c cronExpr
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid cron %q: %w", expr, err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid cron %q: %w", expr, err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid cron %q: %w", expr, err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid cron %q: %w", expr, err)
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid cron %q: %w", expr, err)
+	}
+	// 7 is Sunday too
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar = fields[2] == "*"
+	c.dowStar = fields[4] == "*"
+	return &c, nil
+}
+
+// parseCronField parses a field like "*", "*/15", "1-5", "1,3,5" or "8-18/2"
+// into a bit set of values from lo to hi
+func parseCronField(field string, lo, hi int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("bad step in %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		from, to := lo, hi
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			n, err := strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("bad value %q", part)
+			}
+			from, to = n, n
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("bad range %q", part)
+				}
+			} else if step > 1 {
+				// "5/10" means from 5 to hi every 10
+				to = hi
+			}
+		}
+		if from < lo || to > hi || from > to {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, lo, hi)
+		}
+
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (c *cronExpr) match(t time.Time) bool {
+	if c.minute&(1<<uint(t.Minute())) == 0 ||
+		c.hour&(1<<uint(t.Hour())) == 0 ||
+		c.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+
+	// Like cron: when both day fields are restricted, either may match
+	if !c.domStar && !c.dowStar {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
@@ -0,0 +1,169 @@
+package rotation
+
+import (
+	"testing"
+	"time"
+)
+
+// at returns a local time in 2024, which starts on a Monday
+func at(month time.Month, day, hour, minute int) time.Time {
+	return time.Date(2024, month, day, hour, minute, 0, 0, time.Local)
+}
+
+// bits returns a bit set of values
+func bits(values ...int) uint64 {
+	var b uint64
+	for _, v := range values {
+		b |= 1 << uint(v)
+	}
+	return b
+}
+
+func TestParseCronField(t *testing.T) {
+	tests := []struct {
+		field string
+		want  uint64
+	}{
+		{"*", 1<<60 - 1},
+		{"*/15", bits(0, 15, 30, 45)},
+		{"5", bits(5)},
+		{"1-5", bits(1, 2, 3, 4, 5)},
+		{"1,3,5", bits(1, 3, 5)},
+		{"8-18/4", bits(8, 12, 16)},
+		{"50/5", bits(50, 55)},
+		{"0,30-32", bits(0, 30, 31, 32)},
+	}
+	for _, tt := range tests {
+		got, err := parseCronField(tt.field, 0, 59)
+		if err != nil {
+			t.Errorf("%q: %v", tt.field, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q = %b, want %b", tt.field, got, tt.want)
+		}
+	}
+
+	for _, field := range []string{"60", "-1", "5-1", "*/0", "*/x", "a", "1-b", ""} {
+		if _, err := parseCronField(field, 0, 59); err == nil {
+			t.Errorf("%q: no error", field)
+		}
+	}
+}
+
+func TestCronMatch(t *testing.T) {
+	tests := []struct {
+		expr string
+		t    time.Time
+		want bool
+	}{
+		{"*/15 9-17 * * 1-5", at(1, 1, 9, 15), true},  // Monday
+		{"*/15 9-17 * * 1-5", at(1, 1, 9, 16), false}, // off the quarter hour
+		{"*/15 9-17 * * 1-5", at(1, 1, 18, 0), false}, // after hours
+		{"*/15 9-17 * * 1-5", at(1, 6, 9, 15), false}, // Saturday
+		{"* * * * 7", at(1, 7, 12, 0), true},          // 7 is Sunday
+		{"* * * * 0", at(1, 7, 12, 0), true},          // and so is 0
+		{"* * * 2 *", at(1, 7, 12, 0), false},         // January
+		{"0 12 15 * 1", at(1, 15, 12, 0), true},       // the 15th, also a Monday
+		{"0 12 15 * 3", at(1, 15, 12, 0), true},       // the 15th, not a Wednesday
+		{"0 12 15 * 3", at(1, 17, 12, 0), true},       // a Wednesday, not the 15th
+		{"0 12 15 * 3", at(1, 16, 12, 0), false},      // neither
+		{"0 12 15 * *", at(1, 16, 12, 0), false},      // only the 15th
+		{"30 23 31 12 *", at(12, 31, 23, 30), true},   // New Year's Eve
+		{"30 23 31 12 *", at(12, 31, 23, 31), false},  // a minute late
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.expr)
+		if err != nil {
+			t.Errorf("%q: %v", tt.expr, err)
+			continue
+		}
+		if got := c.match(tt.t); got != tt.want {
+			t.Errorf("%q at %s = %v, want %v", tt.expr, tt.t.Format("Mon 01-02 15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestScheduleActive(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule *Schedule
+		t        time.Time
+		want     bool
+	}{
+		{"nil", nil, at(1, 1, 0, 0), true},
+		{"empty", &Schedule{}, at(1, 1, 0, 0), true},
+
+		{"window before", &Schedule{Windows: []TimeWindow{{"07:00", "09:00"}}}, at(1, 1, 6, 59), false},
+		{"window start", &Schedule{Windows: []TimeWindow{{"07:00", "09:00"}}}, at(1, 1, 7, 0), true},
+		{"window inside", &Schedule{Windows: []TimeWindow{{"07:00", "09:00"}}}, at(1, 1, 8, 59), true},
+		{"window end", &Schedule{Windows: []TimeWindow{{"07:00", "09:00"}}}, at(1, 1, 9, 0), false},
+		{"all day", &Schedule{Windows: []TimeWindow{{"00:00", "24:00"}}}, at(1, 1, 23, 59), true},
+		{"any window", &Schedule{Windows: []TimeWindow{{"07:00", "09:00"}, {"17:00", "19:00"}}}, at(1, 1, 18, 0), true},
+
+		{"overnight evening", &Schedule{Windows: []TimeWindow{{"22:00", "06:00"}}}, at(1, 1, 23, 30), true},
+		{"overnight midnight", &Schedule{Windows: []TimeWindow{{"22:00", "06:00"}}}, at(1, 2, 0, 0), true},
+		{"overnight morning", &Schedule{Windows: []TimeWindow{{"22:00", "06:00"}}}, at(1, 2, 5, 59), true},
+		{"overnight end", &Schedule{Windows: []TimeWindow{{"22:00", "06:00"}}}, at(1, 2, 6, 0), false},
+		{"overnight day", &Schedule{Windows: []TimeWindow{{"22:00", "06:00"}}}, at(1, 2, 12, 0), false},
+
+		{"day", &Schedule{Days: []string{"mon", "FRI"}}, at(1, 5, 12, 0), true},
+		{"other day", &Schedule{Days: []string{"mon", "FRI"}}, at(1, 6, 12, 0), false},
+
+		{"date range", &Schedule{DateRanges: []DateRange{{"2024-03-01", "2024-03-31"}}}, at(3, 31, 23, 59), true},
+		{"date range before", &Schedule{DateRanges: []DateRange{{"2024-03-01", "2024-03-31"}}}, at(2, 29, 12, 0), false},
+		{"date range after", &Schedule{DateRanges: []DateRange{{"2024-03-01", "2024-03-31"}}}, at(4, 1, 0, 0), false},
+		{"other year", &Schedule{DateRanges: []DateRange{{"2023-03-01", "2023-03-31"}}}, at(3, 15, 12, 0), false},
+		{"yearly", &Schedule{DateRanges: []DateRange{{"03-01", "03-31"}}}, at(3, 15, 12, 0), true},
+		{"yearly over new year", &Schedule{DateRanges: []DateRange{{"12-15", "01-05"}}}, at(1, 5, 12, 0), true},
+		{"yearly over new year in december", &Schedule{DateRanges: []DateRange{{"12-15", "01-05"}}}, at(12, 20, 12, 0), true},
+		{"yearly over new year outside", &Schedule{DateRanges: []DateRange{{"12-15", "01-05"}}}, at(1, 6, 12, 0), false},
+
+		// Every kind of rule set must match
+		{"window and day", &Schedule{Windows: []TimeWindow{{"22:00", "06:00"}}, Days: []string{"mon"}}, at(1, 1, 23, 0), true},
+		{"window not day", &Schedule{Windows: []TimeWindow{{"22:00", "06:00"}}, Days: []string{"mon"}}, at(1, 2, 1, 0), false},
+		{"day not cron", &Schedule{Days: []string{"mon"}, Cron: []string{"* 9 * * *"}}, at(1, 1, 10, 0), false},
+
+		// Invalid rules never match
+		{"invalid window", &Schedule{Windows: []TimeWindow{{"7am", "9am"}}}, at(1, 1, 8, 0), false},
+		{"empty window", &Schedule{Windows: []TimeWindow{{"07:00", "07:00"}}}, at(1, 1, 7, 0), false},
+	}
+	for _, tt := range tests {
+		if got := tt.schedule.Active(tt.t); got != tt.want {
+			t.Errorf("%s: Active(%s) = %v, want %v", tt.name, tt.t.Format("Mon 2006-01-02 15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	valid := []Schedule{
+		{},
+		{Windows: []TimeWindow{{"22:00", "06:00"}, {"00:00", "24:00"}}},
+		{Days: []string{"Sun", "sat"}},
+		{DateRanges: []DateRange{{"2024-01-01", "2024-12-31"}, {"12-15", "01-05"}}},
+		{Cron: []string{"*/5 8-18 * * 1-5", "0 0 1 1 *"}},
+	}
+	for _, s := range valid {
+		if err := s.Validate(); err != nil {
+			t.Errorf("%s: %v", s.String(), err)
+		}
+	}
+
+	invalid := []Schedule{
+		{Windows: []TimeWindow{{"7:60", "09:00"}}},
+		{Windows: []TimeWindow{{"07:00", "24:01"}}},
+		{Windows: []TimeWindow{{"07:00", "0700"}}},
+		{Windows: []TimeWindow{{"07:00", "07:00"}}},
+		{Days: []string{"monday"}},
+		{DateRanges: []DateRange{{"2024-02-30", "2024-03-01"}}},
+		{DateRanges: []DateRange{{"2024-01-01", "01-05"}}},
+		{Cron: []string{"* * * *"}},
+		{Cron: []string{"* 24 * * *"}},
+		{Cron: []string{"* * 0 * *"}},
+	}
+	for _, s := range invalid {
+		if err := s.Validate(); err == nil {
+			t.Errorf("%s: no error", s.String())
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package rotation
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Strategy picks the app the rotation shows next. Strategies may keep state
+// between slots; the manager calls them under its lock.
+type Strategy interface {
+	// Name is the name the strategy is registered under
+	Name() string
+
+	// Next returns the index of the app to show after current (-1 if none
+	// has been shown yet). eligible reports whether an index may be shown
+	// now; ok is false if none may. Next must not change the strategy's
+	// state: the manager may ask again before the rotation moves on, e.g.
+	// after the app list changes.
+	Next(apps []AppEntry, current int, eligible func(i int) bool) (next int, ok bool)
+
+	// Commit records that the rotation moved on to apps[idx], the last
+	// pick of Next
+	Commit(apps []AppEntry, idx int)
+}
+
+// Built-in strategies
+const (
+	StrategySequential = "sequential" // in list order
+	StrategyShuffle    = "shuffle"    // random order, each app once per round
+	StrategyWeighted   = "weighted"   // random, in proportion to Weight
+	StrategyFrequency  = "frequency"  // apps with Every show every N slots, the rest in order
+)
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = map[string]func() Strategy{
+		StrategySequential: func() Strategy { return &sequential{} },
+		StrategyShuffle:    func() Strategy { return &shuffle{rng: newRand()} },
+		StrategyWeighted:   func() Strategy { return &weighted{rng: newRand()} },
+		StrategyFrequency:  func() Strategy { return &frequency{lastSlot: make(map[string]int)} },
+	}
+)
+
+// RegisterStrategy makes a strategy available by name. The factory is
+// called for every manager that uses it, so state isn't shared.
+func RegisterStrategy(name string, factory func() Strategy) {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+	strategies[name] = factory
+}
+
+// NewStrategy creates a registered strategy; empty means sequential
+func NewStrategy(name string) (Strategy, error) {
+	if name == "" {
+		name = StrategySequential
+	}
+
+	strategiesMu.RLock()
+	factory, ok := strategies[name]
+	strategiesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown rotation strategy %q", name)
+	}
+	return factory(), nil
+}
+
+// StrategyNames lists the registered strategies
+func StrategyNames() []string {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sequential steps through the list in order
+type sequential struct{}
+
+func (s *sequential) Name() string { return StrategySequential }
+
+func (s *sequential) Commit(apps []AppEntry, idx int) {}
+
+func (s *sequential) Next(apps []AppEntry, current int, eligible func(i int) bool) (int, bool) {
+	for i := 1; i <= len(apps); i++ {
+		idx := (current + i) % len(apps)
+		if idx < 0 {
+			idx += len(apps)
+		}
+		if eligible(idx) {
+			return idx, true
+		}
+	}
+	return 0, false
+}
+
+// shuffle deals the apps in a random order, each once per round, without
+// showing the same app twice in a row across rounds
+type shuffle struct {
+	bag []string // app IDs left this round
+	rng *rand.Rand
+}
+
+func (s *shuffle) Name() string { return StrategyShuffle }
+
+func (s *shuffle) Next(apps []AppEntry, current int, eligible func(i int) bool) (int, bool) {
+	if idx, ok := s.first(apps, eligible); ok {
+		return idx, true
+	}
+
+	// Round over: open the next one with any app but the one that ended
+	// the last. Commit deals the rest of the round once it shows.
+	var candidates []int
+	for i := range apps {
+		if i != current && eligible(i) {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		if current >= 0 && current < len(apps) && eligible(current) {
+			return current, true
+		}
+		return 0, false
+	}
+	return candidates[s.rng.Intn(len(candidates))], true
+}
+
+func (s *shuffle) Commit(apps []AppEntry, idx int) {
+	// Apps dealt before the committed one were removed or out of schedule;
+	// they sit this round out
+	id := apps[idx].ID
+	for k, dealt := range s.bag {
+		if dealt == id {
+			s.bag = s.bag[k+1:]
+			return
+		}
+	}
+
+	// The app opened a new round: deal the others
+	s.bag = s.bag[:0]
+	for _, app := range apps {
+		if app.ID != id {
+			s.bag = append(s.bag, app.ID)
+		}
+	}
+	s.rng.Shuffle(len(s.bag), func(i, j int) { s.bag[i], s.bag[j] = s.bag[j], s.bag[i] })
+}
+
+// first returns the first app left in the bag that may show now
+func (s *shuffle) first(apps []AppEntry, eligible func(i int) bool) (int, bool) {
+	for _, id := range s.bag {
+		if idx := indexOf(apps, id); idx >= 0 && eligible(idx) {
+			return idx, true
+		}
+	}
+	return 0, false
+}
+
+// weighted picks at random in proportion to each app's Weight (default 1),
+// avoiding the app on screen when there is a choice
+type weighted struct {
+	rng *rand.Rand
+}
+
+func (s *weighted) Name() string { return StrategyWeighted }
+
+func (s *weighted) Commit(apps []AppEntry, idx int) {}
+
+func (s *weighted) Next(apps []AppEntry, current int, eligible func(i int) bool) (int, bool) {
+	var candidates []int
+	total := 0
+	for i, app := range apps {
+		if eligible(i) && app.weight() > 0 {
+			candidates = append(candidates, i)
+			total += app.weight()
+		}
+	}
+	if len(candidates) == 0 {
+		return 0, false
+	}
+	if len(candidates) > 1 {
+		for k, idx := range candidates {
+			if idx == current {
+				total -= apps[idx].weight()
+				candidates = append(candidates[:k], candidates[k+1:]...)
+				break
+			}
+		}
+	}
+
+	pick := s.rng.Intn(total)
+	for _, idx := range candidates {
+		if pick < apps[idx].weight() {
+			return idx, true
+		}
+		pick -= apps[idx].weight()
+	}
+	return candidates[len(candidates)-1], true
+}
+
+// frequency shows apps with Every set once every Every slots (e.g. the clock
+// every third slot) and fills the other slots with the remaining apps in
+// order
+type frequency struct {
+	slot     int
+	lastSlot map[string]int // slot each app with Every was last shown in
+	cursor   int            // last index shown from the regular apps
+}
+
+func (s *frequency) Name() string { return StrategyFrequency }
+
+func (s *frequency) Next(apps []AppEntry, current int, eligible func(i int) bool) (int, bool) {
+	slot := s.slot + 1
+
+	// The most overdue app with Every wins the slot
+	due, overdue := -1, 0
+	for i, app := range apps {
+		if app.Every <= 0 || !eligible(i) {
+			continue
+		}
+		last, shown := s.lastSlot[app.ID]
+		since := slot - last
+		if !shown {
+			since = app.Every
+		}
+		if since >= app.Every && since-app.Every >= overdue {
+			due, overdue = i, since-app.Every
+		}
+	}
+	if due >= 0 {
+		return due, true
+	}
+
+	// Otherwise the next regular app in order
+	for i := 1; i <= len(apps); i++ {
+		idx := (s.cursor + i) % len(apps)
+		if apps[idx].Every <= 0 && eligible(idx) {
+			return idx, true
+		}
+	}
+
+	// Only apps with Every can show; take the one shown longest ago
+	best, bestLast := -1, 0
+	for i, app := range apps {
+		if app.Every > 0 && eligible(i) {
+			if last := s.lastSlot[app.ID]; best < 0 || last < bestLast {
+				best, bestLast = i, last
+			}
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	return best, true
+}
+
+func (s *frequency) Commit(apps []AppEntry, idx int) {
+	s.slot++
+	if apps[idx].Every > 0 {
+		s.lastSlot[apps[idx].ID] = s.slot
+	} else {
+		s.cursor = idx
+	}
+}
+
+// newRand returns a random source for a strategy; the manager's lock guards
+// it
+func newRand() *rand.Rand {
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
+
+func indexOf(apps []AppEntry, id string) int {
+	for i, app := range apps {
+		if app.ID == id {
+			return i
+		}
+	}
+	return -1
+}
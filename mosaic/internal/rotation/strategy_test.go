@@ -0,0 +1,204 @@
+package rotation
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// entries returns enabled rotation entries with the given IDs
+func entries(ids ...string) []AppEntry {
+	apps := make([]AppEntry, len(ids))
+	for i, id := range ids {
+		apps[i] = AppEntry{ID: id, Name: id, Enabled: true}
+	}
+	return apps
+}
+
+func always(int) bool { return true }
+
+// play runs a strategy for n slots, starting from current, and returns the
+// IDs it showed
+func play(s Strategy, apps []AppEntry, current, n int, eligible func(i int) bool) []string {
+	var shown []string
+	for i := 0; i < n; i++ {
+		idx, ok := s.Next(apps, current, eligible)
+		if !ok {
+			break
+		}
+		s.Commit(apps, idx)
+		shown = append(shown, apps[idx].ID)
+		current = idx
+	}
+	return shown
+}
+
+func TestSequential(t *testing.T) {
+	apps := entries("a", "b", "c")
+	s := &sequential{}
+
+	if got := play(s, apps, -1, 5, always); !reflect.DeepEqual(got, []string{"a", "b", "c", "a", "b"}) {
+		t.Errorf("shown %v", got)
+	}
+	notB := func(i int) bool { return apps[i].ID != "b" }
+	if got := play(s, apps, 0, 3, notB); !reflect.DeepEqual(got, []string{"c", "a", "c"}) {
+		t.Errorf("without b, shown %v", got)
+	}
+	if _, ok := s.Next(apps, 0, func(int) bool { return false }); ok {
+		t.Error("picked an app when none is eligible")
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	apps := entries("a", "b", "c", "d", "e")
+	s := &shuffle{rng: rand.New(rand.NewSource(1))}
+
+	// Every round shows each app once, and no app twice in a row
+	shown := play(s, apps, -1, 10*len(apps), always)
+	if len(shown) != 10*len(apps) {
+		t.Fatalf("shown %d apps, want %d", len(shown), 10*len(apps))
+	}
+	for round := 0; round < 10; round++ {
+		seen := make(map[string]bool)
+		for _, id := range shown[round*len(apps) : (round+1)*len(apps)] {
+			if seen[id] {
+				t.Errorf("round %d: %s shown twice in %v", round, id, shown)
+			}
+			seen[id] = true
+		}
+	}
+	for i := 1; i < len(shown); i++ {
+		if shown[i] == shown[i-1] {
+			t.Errorf("%s shown twice in a row at %d", shown[i], i)
+		}
+	}
+}
+
+func TestShuffleNextKeepsState(t *testing.T) {
+	apps := entries("a", "b", "c")
+	s := &shuffle{rng: rand.New(rand.NewSource(1))}
+
+	// Asking at the end of a round doesn't deal the next one
+	for _, bag := range [][]string{nil, {}} {
+		s.bag = bag
+		if _, ok := s.Next(apps, 0, always); !ok {
+			t.Fatal("no pick")
+		}
+		if !reflect.DeepEqual(s.bag, bag) {
+			t.Errorf("bag = %v after Next, want %v", s.bag, bag)
+		}
+	}
+
+	// Nor does asking mid-round use up the bag
+	s.bag = []string{"c", "b"}
+	for i := 0; i < 2; i++ {
+		if idx, _ := s.Next(apps, 0, always); apps[idx].ID != "c" {
+			t.Errorf("picked %s, want c", apps[idx].ID)
+		}
+	}
+	s.Commit(apps, 2)
+	if !reflect.DeepEqual(s.bag, []string{"b"}) {
+		t.Errorf("bag = %v after Commit, want [b]", s.bag)
+	}
+}
+
+func TestShuffleSkipsUnscheduled(t *testing.T) {
+	apps := entries("a", "b", "c")
+	s := &shuffle{rng: rand.New(rand.NewSource(1))}
+	s.bag = []string{"b", "c"}
+
+	// b is out of schedule: c shows, and b sits out the rest of the round
+	notB := func(i int) bool { return apps[i].ID != "b" }
+	if got := play(s, apps, 0, 1, notB); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("shown %v, want [c]", got)
+	}
+	if len(s.bag) != 0 {
+		t.Errorf("bag = %v, want the round over", s.bag)
+	}
+
+	// The only app left is shown again rather than nothing
+	onlyA := func(i int) bool { return apps[i].ID == "a" }
+	if got := play(s, apps, 0, 2, onlyA); !reflect.DeepEqual(got, []string{"a", "a"}) {
+		t.Errorf("shown %v, want [a a]", got)
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	apps := entries("a", "b", "c")
+	apps[1].Weight = 3
+	apps[2].Weight = -1 // never picked
+	s := &weighted{rng: rand.New(rand.NewSource(1))}
+
+	counts := make(map[string]int)
+	for i := 0; i < 4000; i++ {
+		idx, ok := s.Next(apps, -1, always)
+		if !ok {
+			t.Fatal("no pick")
+		}
+		counts[apps[idx].ID]++
+	}
+	if counts["c"] != 0 {
+		t.Errorf("c picked %d times", counts["c"])
+	}
+	if counts["b"] < 2800 || counts["b"] > 3200 {
+		t.Errorf("b picked %d of 4000 times, want about 3000", counts["b"])
+	}
+
+	// The app on screen isn't picked again while there is a choice
+	for i := 0; i < 100; i++ {
+		if idx, _ := s.Next(apps, 1, always); idx != 0 {
+			t.Fatalf("picked %s after b, want a", apps[idx].ID)
+		}
+	}
+	if idx, ok := s.Next(apps, 1, func(i int) bool { return i == 1 }); !ok || idx != 1 {
+		t.Errorf("picked %d, %v; want b as the only choice", idx, ok)
+	}
+}
+
+func TestFrequency(t *testing.T) {
+	apps := entries("a", "clock", "b", "c")
+	apps[1].Every = 3
+	s := &frequency{lastSlot: make(map[string]int)}
+
+	want := []string{"clock", "b", "c", "clock", "a", "b", "clock", "c", "a", "clock"}
+	if got := play(s, apps, -1, len(want), always); !reflect.DeepEqual(got, want) {
+		t.Errorf("shown %v, want %v", got, want)
+	}
+
+	// Asking again before a commit gives the same pick
+	first, _ := s.Next(apps, 1, always)
+	second, _ := s.Next(apps, 1, always)
+	if first != second {
+		t.Errorf("picked %s then %s", apps[first].ID, apps[second].ID)
+	}
+
+	// With only clocks scheduled, the one shown longest ago
+	clocks := entries("clock", "weather")
+	clocks[0].Every, clocks[1].Every = 5, 5
+	s = &frequency{lastSlot: map[string]int{"clock": 4, "weather": 3}, slot: 5}
+	if idx, _ := s.Next(clocks, 0, always); clocks[idx].ID != "weather" {
+		t.Errorf("picked %s, want weather", clocks[idx].ID)
+	}
+}
+
+func TestManagerCommitsFirstApp(t *testing.T) {
+	m := NewManager(0)
+	m.SetStrategy(&shuffle{rng: rand.New(rand.NewSource(1))})
+	m.SetApps(entries("a", "b", "c", "d"))
+
+	first := m.FirstApp()
+	if first == nil || first.ID != "a" {
+		t.Fatalf("first app = %v, want a", first)
+	}
+
+	// The first round deals the others after the app shown first
+	seen := map[string]bool{first.ID: true}
+	for i := 0; i < 3; i++ {
+		m.advance()
+		id := m.apps[m.currentIndex].ID
+		if seen[id] {
+			t.Errorf("%s shown twice in the first round", id)
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/johnfernkas/mosaic-addon/internal/brightness"
+	"github.com/johnfernkas/mosaic-addon/internal/display"
+)
+
+// handleGetBrightnessSchedule returns the brightness schedule along with
+// today's sunrise and sunset, so sun offsets are easy to check. The level and
+// times are a display's, in its own timezone: ?display=, else the first one.
+func (s *Server) handleGetBrightnessSchedule(w http.ResponseWriter, r *http.Request) {
+	if s.config == nil {
+		http.Error(w, "Config not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	var disp *display.Display
+	if displayID := r.URL.Query().Get("display"); displayID != "" {
+		if disp = s.getDisplay(displayID); disp == nil {
+			http.Error(w, "Display not found", http.StatusNotFound)
+			return
+		}
+	} else {
+		disp = s.getFirstDisplay()
+	}
+
+	loc := s.config.Location("")
+	resp := map[string]interface{}{
+		"schedule": nil,
+	}
+	if disp != nil {
+		loc = s.config.Location(disp.ID)
+		resp["display"] = disp.ID
+	}
+	resp["timezone"] = loc.String()
+
+	if schedule := s.config.GetBrightnessSchedule(); schedule != nil {
+		now := time.Now().In(loc)
+		level, next := schedule.Level(now)
+		resp["schedule"] = schedule
+		resp["level"] = level
+		if !next.IsZero() {
+			resp["next_change"] = next
+		}
+		if rise, set, ok := brightness.SunTimes(now, schedule.Latitude, schedule.Longitude); ok {
+			resp["sunrise"] = rise
+			resp["sunset"] = set
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) handleSetBrightnessSchedule(w http.ResponseWriter, r *http.Request) {
+	if s.config == nil {
+		http.Error(w, "Config not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	var schedule brightness.Schedule
+	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := schedule.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.config.SetBrightnessSchedule(&schedule); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// A new schedule replaces any manual overrides
+	for _, disp := range s.displays {
+		disp.ResumeBrightnessSchedule()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok", "schedule": schedule})
+}
+
+func (s *Server) handleDeleteBrightnessSchedule(w http.ResponseWriter, r *http.Request) {
+	if s.config == nil {
+		http.Error(w, "Config not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.config.SetBrightnessSchedule(nil); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Back to the fixed brightness the schedule replaced
+	for _, disp := range s.displays {
+		disp.EndBrightnessSchedule()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok"})
+}
+
+// handleResumeDisplayBrightness ends a manual brightness override early
+func (s *Server) handleResumeDisplayBrightness(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	disp.ResumeBrightnessSchedule()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "ok",
+		"brightness": disp.GetBrightness(),
+	})
+}
@@ -187,6 +187,7 @@ const dashboardHTML = `<!DOCTYPE html>
         .app-item:hover { background: #21262d; }
         .app-name { font-weight: 500; }
         .app-meta { font-size: 0.75rem; color: #8b949e; margin-top: 0.25rem; }
+        .app-item.inactive .app-name { color: #8b949e; }
         .app-actions { display: flex; gap: 0.5rem; }
         .app-actions button { padding: 0.25rem 0.5rem; font-size: 0.75rem; }
         
@@ -410,7 +411,12 @@ const dashboardHTML = `<!DOCTYPE html>
                     ...options,
                     headers: { 'Content-Type': 'application/json', ...options.headers },
                 });
-                if (!resp.ok) throw new Error('API error: ' + resp.status);
+                if (!resp.ok) {
+                    // Invalid configs list what's wrong with each field
+                    const body = await resp.json().catch(() => null);
+                    const details = body && body.errors ? ': ' + body.errors.map(e => e.field + ' ' + e.message).join(', ') : '';
+                    throw new Error('API error: ' + resp.status + details);
+                }
                 return await resp.json();
             } catch (e) {
                 console.error('API error:', e);
@@ -429,23 +435,14 @@ const dashboardHTML = `<!DOCTYPE html>
         // Fetch and render frame
         async function fetchFrame() {
             try {
-                const resp = await fetch(getBaseUrl() + 'frame?display=' + currentDisplayId);
-                const buffer = await resp.arrayBuffer();
-                const pixels = new Uint8Array(buffer);
-                const width = parseInt(resp.headers.get('X-Frame-Width') || '64');
-                const height = parseInt(resp.headers.get('X-Frame-Height') || '32');
+                // The preview shows the app's colors, before panel calibration
+                const resp = await fetch(getBaseUrl() + 'frame/preview?display=' + currentDisplayId);
+                const image = await createImageBitmap(await resp.blob());
                 const appName = resp.headers.get('X-App-Name') || 'unknown';
                 
-                canvas.width = width;
-                canvas.height = height;
-                const imageData = ctx.createImageData(width, height);
-                for (let i = 0; i < width * height; i++) {
-                    imageData.data[i * 4] = pixels[i * 3];
-                    imageData.data[i * 4 + 1] = pixels[i * 3 + 1];
-                    imageData.data[i * 4 + 2] = pixels[i * 3 + 2];
-                    imageData.data[i * 4 + 3] = 255;
-                }
-                ctx.putImageData(imageData, 0, 0);
+                canvas.width = image.width;
+                canvas.height = image.height;
+                ctx.drawImage(image, 0, 0);
                 document.getElementById('currentApp').textContent = appName;
             } catch (e) {
                 console.error('Frame error:', e);
@@ -480,9 +477,12 @@ const dashboardHTML = `<!DOCTYPE html>
                     container.innerHTML = '<div class="empty">No apps in rotation. Add some from the App Browser below.</div>';
                     return;
                 }
+                const active = data.active || [];
                 container.innerHTML = data.apps.map(app => 
-                    '<div class="app-item">' +
-                        '<div><div class="app-name">' + (app.name || app.id) + '</div></div>' +
+                    '<div class="app-item' + (active.includes(app.id) ? '' : ' inactive') + '">' +
+                        '<div><div class="app-name">' + (app.name || app.id) + '</div>' +
+                        '<div class="app-meta">' + describeSchedule(app.schedule) +
+                            (active.includes(app.id) ? '' : ' · not showing now') + '</div></div>' +
                         '<div class="app-actions">' +
                             '<button class="danger" onclick="removeFromRotation(\'' + app.id + '\')">Remove</button>' +
                         '</div>' +
@@ -493,6 +493,17 @@ const dashboardHTML = `<!DOCTYPE html>
             }
         }
         
+        // Summarize a rotation schedule, e.g. "mon,tue 07:00-09:00"
+        function describeSchedule(s) {
+            if (!s) return 'Always';
+            const parts = [];
+            if (s.days && s.days.length) parts.push(s.days.join(', '));
+            (s.windows || []).forEach(w => parts.push(w.start + '–' + w.end));
+            (s.date_ranges || []).forEach(r => parts.push(r.start + ' to ' + r.end));
+            (s.cron || []).forEach(c => parts.push('cron ' + c));
+            return parts.length ? parts.join(' · ') : 'Always';
+        }
+        
         // Fetch installed apps
         async function fetchInstalledApps() {
             try {
@@ -507,6 +518,7 @@ const dashboardHTML = `<!DOCTYPE html>
                         '<div><div class="app-name">' + (app.name || app.id) + '</div>' +
                         '<div class="app-meta">' + (app.summary || '') + '</div></div>' +
                         '<div class="app-actions">' +
+                            (app.update_available ? '<button onclick="updateApp(\'' + app.id + '\')">Update</button>' : '') +
                             '<button onclick="addToRotation(\'' + app.id + '\')">Add</button>' +
                             '<button class="danger" onclick="uninstallApp(\'' + app.id + '\')">Uninstall</button>' +
                         '</div>' +
@@ -571,6 +583,14 @@ const dashboardHTML = `<!DOCTYPE html>
             } catch (e) { showError('Failed to install app: ' + e.message); }
         }
         
+        async function updateApp(appId) {
+            if (!confirm('Update ' + appId + ' to the latest version?')) return;
+            try {
+                await api('api/apps/' + appId + '/update', { method: 'POST' });
+                fetchInstalledApps();
+            } catch (e) { showError('Failed to update: ' + e.message); }
+        }
+        
         async function uninstallApp(appId) {
             if (!confirm('Uninstall ' + appId + '?')) return;
             try {
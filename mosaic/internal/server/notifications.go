@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/johnfernkas/mosaic-addon/internal/rotation"
+)
+
+// notificationResponse converts a queued notification for the API
+func notificationResponse(n rotation.NotificationStatus) map[string]interface{} {
+	resp := map[string]interface{}{
+		"id":             n.ID,
+		"priority":       n.Priority.String(),
+		"showing":        n.Showing,
+		"duration_secs":  int(n.Duration.Seconds()),
+		"remaining_secs": int(n.Remaining.Seconds()),
+		"created":        n.Created,
+	}
+	if n.Text != "" {
+		resp["text"] = n.Text
+		resp["color"] = n.Color
+	}
+	if n.AppID != "" {
+		resp["app_id"] = n.AppID
+	}
+	return resp
+}
+
+func (s *Server) handleListNotifications(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	queue := disp.GetNotifications()
+	notifications := make([]map[string]interface{}, 0, len(queue))
+	for _, n := range queue {
+		notifications = append(notifications, notificationResponse(n))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"notifications": notifications})
+}
+
+func (s *Server) handleGetNotification(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	n, ok := disp.GetNotification(chi.URLParam(r, "notificationID"))
+	if !ok {
+		http.Error(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notificationResponse(*n))
+}
+
+// handleClearNotifications clears the queue; sticky notifications are kept
+// unless ?sticky=true
+func (s *Server) handleClearNotifications(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	includeSticky, _ := strconv.ParseBool(r.URL.Query().Get("sticky"))
+	disp.ClearNotifications(includeSticky)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok"})
+}
+
+// handleUpdateNotification changes a notification's text or color, sets the
+// time it has left (duration) or extends it (extend), both in seconds
+func (s *Server) handleUpdateNotification(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		Text     *string `json:"text"`
+		Color    *string `json:"color"`
+		Duration *int    `json:"duration"`
+		Extend   int     `json:"extend"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if (req.Duration != nil && *req.Duration < 0) || req.Extend < 0 {
+		http.Error(w, "duration and extend must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	notificationID := chi.URLParam(r, "notificationID")
+	if _, ok := disp.GetNotification(notificationID); !ok {
+		http.Error(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+
+	n, err := disp.UpdateNotification(notificationID, req.Text, req.Color, req.Duration, req.Extend)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notificationResponse(*n))
+}
+
+func (s *Server) handleDismissNotification(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	if err := disp.DismissNotification(chi.URLParam(r, "notificationID")); err != nil {
+		http.Error(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok"})
+}
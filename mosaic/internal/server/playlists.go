@@ -0,0 +1,252 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/johnfernkas/mosaic-addon/internal/display"
+	"github.com/johnfernkas/mosaic-addon/internal/rotation"
+)
+
+// playlistEntry is a rotation entry in a playlist request. Only the app ID
+// is required; name, path and config come from the installed app and
+// entries are enabled unless they say otherwise.
+type playlistEntry struct {
+	rotation.AppEntry
+	Enabled *bool `json:"enabled"`
+}
+
+// resolvePlaylist turns request entries into rotation entries
+func (s *Server) resolvePlaylist(entries []playlistEntry) ([]rotation.AppEntry, error) {
+	apps := make([]rotation.AppEntry, 0, len(entries))
+	for _, e := range entries {
+		entry := e.AppEntry
+		entry.Enabled = e.Enabled == nil || *e.Enabled
+
+		if entry.Path == "" {
+			app := s.apps.Get(entry.ID)
+			if app == nil {
+				return nil, fmt.Errorf("app %q not installed", entry.ID)
+			}
+			entry.Path = app.Path
+			if entry.Name == "" {
+				entry.Name = app.Name
+			}
+			if entry.Config == nil {
+				entry.Config = app.Config
+			}
+		}
+		if err := entry.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid entry for %s: %w", entry.ID, err)
+		}
+		apps = append(apps, entry)
+	}
+	return apps, nil
+}
+
+func (s *Server) handleListPlaylists(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	playlists := disp.GetPlaylists()
+	playlists[display.DefaultPlaylist], _ = disp.GetPlaylist(display.DefaultPlaylist)
+
+	names := make([]string, 0, len(playlists))
+	for name := range playlists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	active := disp.ActivePlaylist()
+	result := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		result = append(result, map[string]interface{}{
+			"name":   name,
+			"apps":   playlists[name],
+			"active": name == active,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"active":    active,
+		"playlists": result,
+	})
+}
+
+// handleCreatePlaylist creates a playlist. Without apps it starts as a copy
+// of the current rotation.
+func (s *Server) handleCreatePlaylist(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		Name     string          `json:"name"`
+		Apps     []playlistEntry `json:"apps"`
+		Activate bool            `json:"activate"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := display.ValidatePlaylistName(req.Name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, exists := disp.GetPlaylist(req.Name); exists {
+		http.Error(w, "Playlist already exists", http.StatusConflict)
+		return
+	}
+
+	apps := disp.GetRotationApps()
+	if req.Apps != nil {
+		var err error
+		if apps, err = s.resolvePlaylist(req.Apps); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := disp.SavePlaylist(req.Name, apps); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if req.Activate {
+		if err := disp.ActivatePlaylist(req.Name); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":   req.Name,
+		"apps":   apps,
+		"active": disp.ActivePlaylist() == req.Name,
+	})
+}
+
+func (s *Server) handleGetPlaylist(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	apps, ok := disp.GetPlaylist(name)
+	if !ok {
+		http.Error(w, "Playlist not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":   name,
+		"apps":   apps,
+		"active": disp.ActivePlaylist() == name,
+	})
+}
+
+func (s *Server) handleUpdatePlaylist(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	if _, ok := disp.GetPlaylist(name); !ok {
+		http.Error(w, "Playlist not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		Apps []playlistEntry `json:"apps"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	apps, err := s.resolvePlaylist(req.Apps)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := disp.SavePlaylist(name, apps); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":   name,
+		"apps":   apps,
+		"active": disp.ActivePlaylist() == name,
+	})
+}
+
+func (s *Server) handleDeletePlaylist(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	if _, ok := disp.GetPlaylist(name); !ok {
+		http.Error(w, "Playlist not found", http.StatusNotFound)
+		return
+	}
+	if err := disp.DeletePlaylist(name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "ok",
+		"active": disp.ActivePlaylist(),
+	})
+}
+
+// handleActivatePlaylist switches the display to a playlist immediately;
+// activate "default" to go back to the shared rotation
+func (s *Server) handleActivatePlaylist(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	if _, ok := disp.GetPlaylist(name); !ok {
+		http.Error(w, "Playlist not found", http.StatusNotFound)
+		return
+	}
+	if err := disp.ActivatePlaylist(name); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok", "active": name})
+}
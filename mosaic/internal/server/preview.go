@@ -0,0 +1,215 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/johnfernkas/mosaic-addon/internal/display"
+	"github.com/tidbyt/go-libwebp/webp"
+)
+
+const maxPreviewScale = 32
+
+// previewOptions controls how a frame is turned into an image
+type previewOptions struct {
+	Format string // "png", "gif" or "webp"
+	Scale  int    // integer upscale factor
+	LED    bool   // draw each pixel as a round LED dot
+	Frame  int    // frame index for still images
+}
+
+// handleFramePreview encodes the current frame of a display as an image.
+// PNG returns a single frame, GIF and WebP return the whole animation.
+func (s *Server) handleFramePreview(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	opts := previewOptions{
+		Format: q.Get("format"),
+		Scale:  1,
+		LED:    q.Get("style") == "led",
+	}
+	if opts.Format == "" {
+		opts.Format = "png"
+	}
+	if v := q.Get("scale"); v != "" {
+		scale, err := strconv.Atoi(v)
+		if err != nil || scale < 1 || scale > maxPreviewScale {
+			http.Error(w, fmt.Sprintf("scale must be an integer between 1 and %d", maxPreviewScale), http.StatusBadRequest)
+			return
+		}
+		opts.Scale = scale
+	}
+	if v := q.Get("frame"); v != "" {
+		idx, err := strconv.Atoi(v)
+		if err != nil || idx < 0 {
+			http.Error(w, "frame must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		opts.Frame = idx
+	}
+
+	displayID := q.Get("display")
+	if displayID == "" {
+		displayID = "default"
+	}
+
+	var frame *display.FrameData
+	if disp := s.getDisplay(displayID); disp != nil {
+		frame = disp.GetFrame().Uncalibrated()
+	} else {
+		frame = s.generateFallbackFrame()
+	}
+
+	data, contentType, err := encodePreview(frame, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	setFrameHeaders(w, frame)
+	w.Write(data)
+}
+
+// encodePreview renders a frame in the requested image format
+func encodePreview(frame *display.FrameData, opts previewOptions) ([]byte, string, error) {
+	images := frame.Images()
+	if len(images) == 0 {
+		return nil, "", fmt.Errorf("frame has no pixels")
+	}
+
+	scaled := make([]*image.RGBA, len(images))
+	for i, img := range images {
+		scaled[i] = upscale(img, opts.Scale, opts.LED)
+	}
+
+	delay := frame.DelayMs
+	if delay <= 0 {
+		delay = 50
+	}
+
+	var buf bytes.Buffer
+	switch opts.Format {
+	case "png":
+		idx := opts.Frame
+		if idx >= len(scaled) {
+			return nil, "", fmt.Errorf("frame %d out of range (frame count %d)", idx, len(scaled))
+		}
+		if err := png.Encode(&buf, scaled[idx]); err != nil {
+			return nil, "", fmt.Errorf("encoding png: %w", err)
+		}
+		return buf.Bytes(), "image/png", nil
+
+	case "gif":
+		anim := &gif.GIF{LoopCount: 0}
+		for _, img := range scaled {
+			anim.Image = append(anim.Image, toPaletted(img))
+			// GIF delays are in hundredths of a second; browsers clamp anything below 2
+			anim.Delay = append(anim.Delay, max(delay/10, 2))
+		}
+		if err := gif.EncodeAll(&buf, anim); err != nil {
+			return nil, "", fmt.Errorf("encoding gif: %w", err)
+		}
+		return buf.Bytes(), "image/gif", nil
+
+	case "webp":
+		bounds := scaled[0].Bounds()
+		enc, err := webp.NewAnimationEncoder(bounds.Dx(), bounds.Dy(), 0, 0)
+		if err != nil {
+			return nil, "", fmt.Errorf("initializing webp encoder: %w", err)
+		}
+		defer enc.Close()
+
+		for _, img := range scaled {
+			if err := enc.AddFrame(img, time.Duration(delay)*time.Millisecond); err != nil {
+				return nil, "", fmt.Errorf("adding webp frame: %w", err)
+			}
+		}
+		data, err := enc.Assemble()
+		if err != nil {
+			return nil, "", fmt.Errorf("encoding webp: %w", err)
+		}
+		return data, "image/webp", nil
+	}
+
+	return nil, "", fmt.Errorf("unsupported format %q (use png, gif or webp)", opts.Format)
+}
+
+// upscale enlarges an image by an integer factor. With led set, each pixel
+// becomes a round dot on a black background, like a real matrix panel.
+func upscale(img *image.RGBA, scale int, led bool) *image.RGBA {
+	if scale <= 1 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, bounds.Dx()*scale, bounds.Dy()*scale))
+	draw.Draw(out, out.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	// Dots only read as dots when there are a few pixels to work with
+	dots := led && scale >= 3
+	center := float64(scale) / 2
+	radius := float64(scale) * 0.42
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			c := img.RGBAAt(x, y)
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					if dots {
+						fx := float64(dx) + 0.5 - center
+						fy := float64(dy) + 0.5 - center
+						if fx*fx+fy*fy > radius*radius {
+							continue
+						}
+					}
+					out.SetRGBA(x*scale+dx, y*scale+dy, c)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// toPaletted converts an image for GIF encoding. Frames with 256 colors or
+// fewer keep their exact colors, anything else is dithered to a web palette.
+func toPaletted(img *image.RGBA) *image.Paletted {
+	bounds := img.Bounds()
+
+	seen := make(map[color.RGBA]struct{})
+	pal := make(color.Palette, 0, 256)
+	for y := bounds.Min.Y; y < bounds.Max.Y && pal != nil; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			if _, ok := seen[c]; ok {
+				continue
+			}
+			if len(pal) == 256 {
+				pal = nil
+				break
+			}
+			seen[c] = struct{}{}
+			pal = append(pal, c)
+		}
+	}
+
+	if pal == nil {
+		out := image.NewPaletted(bounds, palette.WebSafe)
+		draw.FloydSteinberg.Draw(out, bounds, img, bounds.Min)
+		return out
+	}
+
+	out := image.NewPaletted(bounds, pal)
+	draw.Draw(out, bounds, img, bounds.Min, draw.Src)
+	return out
+}
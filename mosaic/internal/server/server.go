@@ -2,15 +2,23 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/johnfernkas/mosaic-addon/internal/apps"
+	"github.com/johnfernkas/mosaic-addon/internal/calibration"
 	"github.com/johnfernkas/mosaic-addon/internal/config"
 	"github.com/johnfernkas/mosaic-addon/internal/display"
+	"github.com/johnfernkas/mosaic-addon/internal/output"
+	"github.com/johnfernkas/mosaic-addon/internal/pixlet"
 	"github.com/johnfernkas/mosaic-addon/internal/rotation"
 )
 
@@ -24,6 +32,9 @@ type Server struct {
 	config   *config.Config
 	apps     *apps.Repository
 	displays map[string]*display.Display
+
+	renderCache *pixlet.Cache
+	renderPool  *pixlet.Pool
 }
 
 // New creates a new Mosaic server
@@ -39,16 +50,22 @@ func New(dataDir string) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
+	appRepo.SetSource(apps.SourceFromConfig(cfg.CommunitySource))
 
 	s := &Server{
 		router:   chi.NewRouter(),
 		config:   cfg,
 		apps:     appRepo,
 		displays: make(map[string]*display.Display),
+
+		renderCache: pixlet.NewCache(time.Duration(cfg.RenderCacheTTL) * time.Second),
+		renderPool:  pixlet.NewPool(cfg.RenderWorkers),
 	}
 
 	s.setupRoutes()
 
+	go s.checkAppUpdates()
+
 	return s, nil
 }
 
@@ -94,38 +111,71 @@ func (s *Server) setupRoutes() {
 		r.Get("/displays/{displayID}", s.handleGetDisplayByID)
 		r.Put("/displays/{displayID}", s.handleUpdateDisplay)
 		r.Put("/displays/{displayID}/brightness", s.handleSetDisplayBrightness)
+		r.Delete("/displays/{displayID}/brightness/override", s.handleResumeDisplayBrightness)
 		r.Put("/displays/{displayID}/power", s.handleSetDisplayPower)
+		r.Get("/displays/{displayID}/calibration", s.handleGetDisplayCalibration)
+		r.Put("/displays/{displayID}/calibration", s.handleSetDisplayCalibration)
+		r.Delete("/displays/{displayID}/calibration", s.handleResetDisplayCalibration)
+		r.Get("/displays/{displayID}/output", s.handleGetDisplayOutput)
+		r.Put("/displays/{displayID}/output", s.handleSetDisplayOutput)
+		r.Delete("/displays/{displayID}/output", s.handleDeleteDisplayOutput)
 		r.Post("/displays/{displayID}/skip", s.handleDisplaySkip)
 		r.Get("/displays/{displayID}/rotation", s.handleGetDisplayRotation)
 		r.Put("/displays/{displayID}/rotation", s.handleSetDisplayRotation)
 		r.Post("/displays/{displayID}/rotation/apps", s.handleAddToDisplayRotation)
+		r.Put("/displays/{displayID}/rotation/apps/{appID}", s.handleUpdateDisplayRotationApp)
 		r.Delete("/displays/{displayID}/rotation/apps/{appID}", s.handleRemoveFromDisplayRotation)
+		r.Get("/displays/{displayID}/playlists", s.handleListPlaylists)
+		r.Post("/displays/{displayID}/playlists", s.handleCreatePlaylist)
+		r.Get("/displays/{displayID}/playlists/{name}", s.handleGetPlaylist)
+		r.Put("/displays/{displayID}/playlists/{name}", s.handleUpdatePlaylist)
+		r.Delete("/displays/{displayID}/playlists/{name}", s.handleDeletePlaylist)
+		r.Post("/displays/{displayID}/playlists/{name}/activate", s.handleActivatePlaylist)
+		r.Get("/displays/{displayID}/notifications", s.handleListNotifications)
+		r.Delete("/displays/{displayID}/notifications", s.handleClearNotifications)
+		r.Get("/displays/{displayID}/notifications/{notificationID}", s.handleGetNotification)
+		r.Patch("/displays/{displayID}/notifications/{notificationID}", s.handleUpdateNotification)
+		r.Delete("/displays/{displayID}/notifications/{notificationID}", s.handleDismissNotification)
+		r.Get("/displays/{displayID}/stream", s.handleFrameStream)
 		
 		// Legacy single display endpoints (use default display)
 		r.Get("/display", s.handleGetDisplay)
 		r.Put("/display/brightness", s.handleSetBrightness)
 		r.Put("/display/power", s.handleSetPower)
 		r.Post("/display/skip", s.handleSkip)
+
+		// Brightness schedule (all displays)
+		r.Get("/brightness/schedule", s.handleGetBrightnessSchedule)
+		r.Put("/brightness/schedule", s.handleSetBrightnessSchedule)
+		r.Delete("/brightness/schedule", s.handleDeleteBrightnessSchedule)
 		
 		// Rotation (legacy - default display)
 		r.Get("/rotation", s.handleGetRotation)
 		r.Put("/rotation", s.handleSetRotation)
 		r.Put("/rotation/enabled", s.handleSetRotationEnabled)
 		r.Post("/rotation/apps", s.handleAddToRotation)
+		r.Put("/rotation/apps/{appID}", s.handleUpdateRotationApp)
 		r.Delete("/rotation/apps/{appID}", s.handleRemoveFromRotation)
 		
 		// Apps
 		r.Get("/apps", s.handleListApps)
 		r.Get("/apps/community", s.handleListCommunity)
 		r.Get("/apps/community/search", s.handleSearchCommunity)
+		r.Post("/apps/community/refresh", s.handleRefreshCommunity)
 		r.Post("/apps/install", s.handleInstallApp)
 		r.Post("/apps/upload", s.handleUploadApp)
 		r.Delete("/apps/{appID}", s.handleUninstallApp)
 		r.Get("/apps/{appID}", s.handleGetApp)
 		r.Put("/apps/{appID}/config", s.handleSaveAppConfig)
+		r.Post("/apps/updates/check", s.handleCheckAppUpdates)
+		r.Post("/apps/{appID}/update/preview", s.handlePreviewAppUpdate)
+		r.Post("/apps/{appID}/update", s.handleUpdateApp)
+		r.Post("/apps/{appID}/rollback", s.handleRollbackApp)
 		
 		// Rendering
 		r.Post("/render", s.handleRenderApp)
+		r.Get("/render/cache", s.handleGetRenderCache)
+		r.Delete("/render/cache", s.handleClearRenderCache)
 		r.Post("/notify", s.handlePushNotify)
 		r.Post("/show", s.handleShowApp)
 	})
@@ -133,6 +183,7 @@ func (s *Server) setupRoutes() {
 	// Frame endpoint for LED matrix clients
 	s.router.Get("/frame", s.handleFrame)
 	s.router.Get("/frame/preview", s.handleFramePreview)
+	s.router.Get("/frame/ws", s.handleFrameStream)
 }
 
 // handleDashboard serves the web UI
@@ -150,6 +201,12 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	status["display_count"] = len(s.displays)
+	if s.renderCache != nil {
+		status["render_cache"] = s.renderCache.Stats()
+	}
+	if s.renderPool != nil {
+		status["render_pool"] = s.renderPool.Stats()
+	}
 	
 	// Show first display info if any exist
 	for _, disp := range s.displays {
@@ -231,6 +288,12 @@ func (s *Server) handleRegisterDisplay(w http.ResponseWriter, r *http.Request) {
 	}
 
 	disp := display.NewDisplay(req.ID, name, width, height, s.config, s.apps)
+	if s.renderCache != nil {
+		disp.SetRenderCache(s.renderCache)
+	}
+	if s.renderPool != nil {
+		disp.SetRenderPool(s.renderPool)
+	}
 	s.displays[req.ID] = disp
 	disp.Start()
 
@@ -262,8 +325,7 @@ func (s *Server) handleGetDisplayByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	frame := disp.GetFrame()
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	resp := map[string]interface{}{
 		"id":               disp.ID,
 		"name":             disp.Name,
 		"width":            disp.Width,
@@ -272,7 +334,14 @@ func (s *Server) handleGetDisplayByID(w http.ResponseWriter, r *http.Request) {
 		"power":            disp.IsPowerOn(),
 		"rotation_enabled": disp.IsRotationEnabled(),
 		"current_app":      frame.AppName,
-	})
+		"timezone":         disp.GetTimezone(),
+	}
+	if until := disp.BrightnessOverride(); !until.IsZero() {
+		resp["brightness_override_until"] = until
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
 }
 
 func (s *Server) handleUpdateDisplay(w http.ResponseWriter, r *http.Request) {
@@ -295,6 +364,12 @@ func (s *Server) handleUpdateDisplay(w http.ResponseWriter, r *http.Request) {
 	if power, ok := req["power"].(bool); ok {
 		disp.SetPower(power)
 	}
+	if tz, ok := req["timezone"].(string); ok {
+		if err := disp.SetTimezone(tz); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok"})
@@ -342,6 +417,118 @@ func (s *Server) handleSetDisplayPower(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{"power": req.Power})
 }
 
+func (s *Server) handleGetDisplayCalibration(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(disp.GetCalibration())
+}
+
+func (s *Server) handleSetDisplayCalibration(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	// Decode over the current profile so partial updates keep other fields
+	profile := disp.GetCalibration()
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := disp.SetCalibration(profile); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+func (s *Server) handleResetDisplayCalibration(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	profile := calibration.Default()
+	if err := disp.SetCalibration(profile); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+func (s *Server) handleGetDisplayOutput(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	target := disp.GetOutput()
+	if target == nil {
+		http.Error(w, "No output bound", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(target)
+}
+
+func (s *Server) handleSetDisplayOutput(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	var target output.Target
+	if err := json.NewDecoder(r.Body).Decode(&target); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := disp.SetOutput(&target); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(disp.GetOutput())
+}
+
+func (s *Server) handleDeleteDisplayOutput(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	if err := disp.SetOutput(nil); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok"})
+}
+
 func (s *Server) handleDisplaySkip(w http.ResponseWriter, r *http.Request) {
 	displayID := chi.URLParam(r, "displayID")
 	disp := s.getDisplay(displayID)
@@ -364,10 +551,7 @@ func (s *Server) handleGetDisplayRotation(w http.ResponseWriter, r *http.Request
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"enabled": disp.IsRotationEnabled(),
-		"apps":    disp.GetRotationApps(),
-	})
+	json.NewEncoder(w).Encode(rotationStatus(disp))
 }
 
 func (s *Server) handleSetDisplayRotation(w http.ResponseWriter, r *http.Request) {
@@ -379,13 +563,20 @@ func (s *Server) handleSetDisplayRotation(w http.ResponseWriter, r *http.Request
 	}
 
 	var req struct {
-		Enabled *bool `json:"enabled,omitempty"`
+		Enabled  *bool   `json:"enabled,omitempty"`
+		Strategy *string `json:"strategy,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
+	if req.Strategy != nil {
+		if err := disp.SetRotationStrategy(*req.Strategy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 	if req.Enabled != nil {
 		disp.SetRotationEnabled(*req.Enabled)
 	}
@@ -419,6 +610,17 @@ func (s *Server) handleAddToDisplayRotation(w http.ResponseWriter, r *http.Reque
 	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok"})
 }
 
+func (s *Server) handleUpdateDisplayRotationApp(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	updateRotationApp(w, r, disp)
+}
+
 func (s *Server) handleRemoveFromDisplayRotation(w http.ResponseWriter, r *http.Request) {
 	displayID := chi.URLParam(r, "displayID")
 	disp := s.getDisplay(displayID)
@@ -535,10 +737,7 @@ func (s *Server) handleGetRotation(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"enabled": s.getFirstDisplay().IsRotationEnabled(),
-		"apps":    s.getFirstDisplay().GetRotationApps(),
-	})
+	json.NewEncoder(w).Encode(rotationStatus(s.getFirstDisplay()))
 }
 
 func (s *Server) handleSetRotation(w http.ResponseWriter, r *http.Request) {
@@ -555,6 +754,13 @@ func (s *Server) handleSetRotation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	for _, app := range req.Apps {
+		if err := app.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid entry for %s: %v", app.ID, err), http.StatusBadRequest)
+			return
+		}
+	}
+
 	if err := s.getFirstDisplay().SetRotationApps(req.Apps); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -610,6 +816,15 @@ func (s *Server) handleAddToRotation(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok"})
 }
 
+func (s *Server) handleUpdateRotationApp(w http.ResponseWriter, r *http.Request) {
+	if s.getFirstDisplay() == nil {
+		http.Error(w, "Display not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	updateRotationApp(w, r, s.getFirstDisplay())
+}
+
 func (s *Server) handleRemoveFromRotation(w http.ResponseWriter, r *http.Request) {
 	if s.getFirstDisplay() == nil {
 		http.Error(w, "Display not initialized", http.StatusInternalServerError)
@@ -626,6 +841,151 @@ func (s *Server) handleRemoveFromRotation(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok"})
 }
 
+// rotationStatus describes a display's rotation, including which entries
+// their schedules allow right now
+func rotationStatus(disp *display.Display) map[string]interface{} {
+	apps := disp.GetRotationApps()
+	active := make([]string, 0, len(apps))
+	for _, app := range apps {
+		if disp.IsScheduled(app) {
+			active = append(active, app.ID)
+		}
+	}
+
+	return map[string]interface{}{
+		"enabled":  disp.IsRotationEnabled(),
+		"apps":     apps,
+		"active":   active,
+		"timezone": disp.GetTimezone(),
+		"playlist": disp.ActivePlaylist(),
+		"strategy": disp.GetRotationStrategy(),
+		"failures": disp.GetFailures(),
+		// Strategies the display can be switched to
+		"strategies": rotation.StrategyNames(),
+	}
+}
+
+// updateRotationApp applies a partial update to the rotation entry named by
+// {appID}. Sending "schedule": null clears the schedule; weight and every
+// tune the weighted and frequency strategies, on_failure and max_failures
+// how render failures are handled.
+func updateRotationApp(w http.ResponseWriter, r *http.Request, disp *display.Display) {
+	var req map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var enabled *bool
+	var dwellMs, weight, every, maxFailures *int
+	var onFailure *rotation.FailurePolicy
+	var schedule *rotation.Schedule
+	if raw, ok := req["enabled"]; ok {
+		if err := json.Unmarshal(raw, &enabled); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if raw, ok := req["dwell_ms"]; ok {
+		if err := json.Unmarshal(raw, &dwellMs); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if raw, ok := req["weight"]; ok {
+		if err := json.Unmarshal(raw, &weight); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if weight != nil && *weight < 0 {
+			http.Error(w, "weight must not be negative", http.StatusBadRequest)
+			return
+		}
+	}
+	if raw, ok := req["every"]; ok {
+		if err := json.Unmarshal(raw, &every); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if every != nil && *every < 0 {
+			http.Error(w, "every must not be negative", http.StatusBadRequest)
+			return
+		}
+	}
+	if raw, ok := req["on_failure"]; ok {
+		if err := json.Unmarshal(raw, &onFailure); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if onFailure != nil {
+			if err := onFailure.Validate(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if raw, ok := req["max_failures"]; ok {
+		if err := json.Unmarshal(raw, &maxFailures); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if maxFailures != nil && *maxFailures < 0 {
+			http.Error(w, "max_failures must not be negative", http.StatusBadRequest)
+			return
+		}
+	}
+	_, setSchedule := req["schedule"]
+	if setSchedule {
+		if err := json.Unmarshal(req["schedule"], &schedule); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := schedule.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid schedule: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	appID := chi.URLParam(r, "appID")
+	var updated rotation.AppEntry
+	err := disp.UpdateRotationApp(appID, func(app *rotation.AppEntry) {
+		if enabled != nil {
+			app.Enabled = *enabled
+		}
+		if dwellMs != nil {
+			app.DwellMs = *dwellMs
+		}
+		if weight != nil {
+			app.Weight = *weight
+		}
+		if every != nil {
+			app.Every = *every
+		}
+		if onFailure != nil {
+			app.OnFailure = *onFailure
+		}
+		if maxFailures != nil {
+			app.MaxFailures = *maxFailures
+		}
+		if setSchedule {
+			app.Schedule = schedule
+		}
+		updated = *app
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "ok",
+		"app":     updated,
+		"active":  disp.IsScheduled(updated),
+		"failure": disp.GetFailures()[updated.ID],
+	})
+}
+
 func (s *Server) handleListApps(w http.ResponseWriter, r *http.Request) {
 	if s.apps == nil {
 		w.Header().Set("Content-Type", "application/json")
@@ -648,6 +1008,28 @@ func (s *Server) handleListCommunity(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(s.apps.ListCommunity())
 }
 
+// handleRefreshCommunity rebuilds the community index from the community
+// source, so new apps show up without a new image
+func (s *Server) handleRefreshCommunity(w http.ResponseWriter, r *http.Request) {
+	if s.apps == nil {
+		http.Error(w, "App repository not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	index, err := s.apps.RefreshCommunityIndex(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "ok",
+		"count":   index.Count,
+		"updated": index.Updated,
+	})
+}
+
 func (s *Server) handleSearchCommunity(w http.ResponseWriter, r *http.Request) {
 	if s.apps == nil {
 		w.Header().Set("Content-Type", "application/json")
@@ -676,7 +1058,7 @@ func (s *Server) handleInstallApp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	app, err := s.apps.Install(req.AppID)
+	app, err := s.apps.Install(r.Context(), req.AppID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -765,6 +1147,16 @@ func (s *Server) handleSaveAppConfig(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.apps.SaveConfig(appID, config); err != nil {
+		var configErrs apps.ConfigErrors
+		if errors.As(err, &configErrs) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":  "Invalid config",
+				"errors": configErrs,
+			})
+			return
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -816,6 +1208,27 @@ func (s *Server) handleRenderApp(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok"})
 }
 
+func (s *Server) handleGetRenderCache(w http.ResponseWriter, r *http.Request) {
+	if s.renderCache == nil {
+		http.Error(w, "Render cache not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.renderCache.Stats())
+}
+
+func (s *Server) handleClearRenderCache(w http.ResponseWriter, r *http.Request) {
+	if s.renderCache == nil {
+		http.Error(w, "Render cache not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	s.renderCache.Clear()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok"})
+}
+
 func (s *Server) handlePushNotify(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Text      string `json:"text"`
@@ -823,6 +1236,21 @@ func (s *Server) handlePushNotify(w http.ResponseWriter, r *http.Request) {
 		Duration  int    `json:"duration"`
 		Priority  string `json:"priority"`
 		DisplayID string `json:"display_id"`
+
+		// Templates
+		Template   string  `json:"template"`
+		Title      string  `json:"title"`
+		Icon       string  `json:"icon"`
+		Font       string  `json:"font"`
+		TitleFont  string  `json:"title_font"`
+		TitleColor string  `json:"title_color"`
+		Background string  `json:"background"`
+		Progress   float64 `json:"progress"`
+		Scroll     *bool   `json:"scroll"` // unset = scroll when the text doesn't fit
+
+		// Installed app, with config overrides
+		AppID  string            `json:"app_id"`
+		Config map[string]string `json:"config"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -848,20 +1276,55 @@ func (s *Server) handlePushNotify(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	priority := rotation.PriorityNormal
-	switch req.Priority {
-	case "low":
-		priority = rotation.PriorityLow
-	case "high":
-		priority = rotation.PriorityHigh
-	case "sticky":
-		priority = rotation.PrioritySticky
+	priority := rotation.ParsePriority(req.Priority)
+
+	if req.AppID != "" {
+		id, err := disp.PushAppNotification(req.AppID, req.Config, req.Duration, priority)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":     "ok",
+			"id":         id,
+			"display_id": disp.ID,
+		})
+		return
+	}
+
+	opts := display.NotifyOptions{
+		Template:   req.Template,
+		Text:       req.Text,
+		Title:      req.Title,
+		Icon:       req.Icon,
+		Font:       req.Font,
+		TitleFont:  req.TitleFont,
+		Color:      req.Color,
+		TitleColor: req.TitleColor,
+		Background: req.Background,
+		Progress:   req.Progress,
+	}
+	if req.Scroll != nil {
+		opts.Scroll = display.ScrollNever
+		if *req.Scroll {
+			opts.Scroll = display.ScrollAlways
+		}
 	}
 
-	disp.PushText(req.Text, req.Color, req.Duration, priority)
+	id, err := disp.PushNotification(r.Context(), opts, req.Duration, priority)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok"})
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "ok",
+		"id":         id,
+		"display_id": disp.ID,
+	})
 }
 
 func (s *Server) handleShowApp(w http.ResponseWriter, r *http.Request) {
@@ -889,7 +1352,13 @@ func (s *Server) handleShowApp(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok"})
 }
 
-// handleFrame serves raw RGB pixels to LED matrix clients
+// maxFrameWait caps how long a long-poll request on /frame may block
+const maxFrameWait = 60 * time.Second
+
+// handleFrame serves raw RGB pixels to LED matrix clients.
+// Supports If-None-Match (answered with 304 when the frame is unchanged) and
+// ?wait=N long-polling, which blocks up to N seconds for the next frame.
+// ?format= and ?encoding= select a compact pixel layout (see parsePixelOptions).
 func (s *Server) handleFrame(w http.ResponseWriter, r *http.Request) {
 	var frame *display.FrameData
 
@@ -899,30 +1368,118 @@ func (s *Server) handleFrame(w http.ResponseWriter, r *http.Request) {
 		displayID = "default"
 	}
 
+	wait, err := parseWait(r.URL.Query().Get("wait"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format, encoding, err := parsePixelOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ifNoneMatch := r.Header.Get("If-None-Match")
+
 	if disp := s.displays[displayID]; disp != nil {
+		// Subscribe before reading the frame so no change slips in between
+		var frames <-chan *display.FrameData
+		if wait > 0 {
+			var unsubscribe func()
+			frames, unsubscribe = disp.Subscribe()
+			defer unsubscribe()
+		}
+
 		frame = disp.GetFrame()
+
+		// Long-poll: block while the client already has this frame
+		if wait > 0 && (ifNoneMatch == "" || etagMatches(ifNoneMatch, frameETag(frame, format, encoding))) {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+
+			select {
+			case next := <-frames:
+				frame = next
+			case <-timer.C:
+			case <-r.Context().Done():
+				return
+			}
+		}
 	} else {
 		// No display registered yet, return fallback
 		frame = s.generateFallbackFrame()
 	}
 
+	etag := frameETag(frame, format, encoding)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if etagMatches(ifNoneMatch, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	pixels, err := frame.Encode(format, encoding)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	// Set response headers
 	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Header().Set("X-Frame-Width", strconv.Itoa(frame.Width))
-	w.Header().Set("X-Frame-Height", strconv.Itoa(frame.Height))
-	w.Header().Set("X-Frame-Count", strconv.Itoa(frame.FrameCount))
-	w.Header().Set("X-Frame-Delay-Ms", strconv.Itoa(frame.DelayMs))
-	w.Header().Set("X-Dwell-Secs", strconv.Itoa(frame.DwellSecs))
-	w.Header().Set("X-Brightness", strconv.Itoa(frame.Brightness))
-	w.Header().Set("X-App-Name", frame.AppName)
+	setFrameHeaders(w, frame)
+	w.Header().Set("X-Frame-Format", string(format))
+	w.Header().Set("X-Frame-Encoding", string(encoding))
+
+	w.Write(pixels)
+}
 
-	w.Write(frame.Pixels)
+// frameETag identifies a frame by its update time, app and brightness, and
+// the format and encoding its pixels are sent in
+func frameETag(frame *display.FrameData, format display.PixelFormat, encoding display.PixelEncoding) string {
+	h := fnv.New32a()
+	h.Write([]byte(frame.AppName))
+	return fmt.Sprintf(`"%x-%x-%d-%s-%s"`, frame.UpdatedAt.UnixNano(), h.Sum32(), frame.Brightness, format, encoding)
 }
 
-// handleFramePreview serves a PNG preview for browser debugging
-func (s *Server) handleFramePreview(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/plain")
-	w.Write([]byte("PNG preview not yet implemented - use /frame for raw pixels"))
+// etagMatches reports whether an If-None-Match header matches the ETag
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseWait parses the ?wait= long-poll timeout, given in seconds or as a
+// Go duration ("30s", "1m")
+func parseWait(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+
+	var wait time.Duration
+	if secs, err := strconv.Atoi(v); err == nil {
+		wait = time.Duration(secs) * time.Second
+	} else if d, err := time.ParseDuration(v); err == nil {
+		wait = d
+	} else {
+		return 0, fmt.Errorf("invalid wait %q", v)
+	}
+
+	if wait < 0 {
+		return 0, fmt.Errorf("invalid wait %q", v)
+	}
+	if wait > maxFrameWait {
+		wait = maxFrameWait
+	}
+	return wait, nil
 }
 
 func (s *Server) generateFallbackFrame() *display.FrameData {
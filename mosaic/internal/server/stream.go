@@ -0,0 +1,169 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"github.com/johnfernkas/mosaic-addon/internal/display"
+)
+
+const (
+	streamWriteTimeout = 10 * time.Second
+	streamPingInterval = 30 * time.Second
+	streamPongTimeout  = 60 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 16 * 1024,
+	// Clients connect directly or through HA ingress, so accept any origin
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// frameMeta describes a frame with the same fields as the X-Frame-* headers
+type frameMeta struct {
+	Width      int       `json:"width"`
+	Height     int       `json:"height"`
+	FrameCount int       `json:"frame_count"`
+	DelayMs    int       `json:"delay_ms"`
+	DwellSecs  int       `json:"dwell_secs"`
+	Brightness int       `json:"brightness"`
+	AppName    string    `json:"app_name"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	Format   display.PixelFormat   `json:"format"`
+	Encoding display.PixelEncoding `json:"encoding"`
+}
+
+// setFrameHeaders sets the X-Frame-* metadata headers for a frame
+func setFrameHeaders(w http.ResponseWriter, frame *display.FrameData) {
+	w.Header().Set("X-Frame-Width", strconv.Itoa(frame.Width))
+	w.Header().Set("X-Frame-Height", strconv.Itoa(frame.Height))
+	w.Header().Set("X-Frame-Count", strconv.Itoa(frame.FrameCount))
+	w.Header().Set("X-Frame-Delay-Ms", strconv.Itoa(frame.DelayMs))
+	w.Header().Set("X-Dwell-Secs", strconv.Itoa(frame.DwellSecs))
+	w.Header().Set("X-Brightness", strconv.Itoa(frame.Brightness))
+	w.Header().Set("X-App-Name", frame.AppName)
+}
+
+// parsePixelOptions reads the ?format= (rgb888, rgb565) and ?encoding=
+// (none, zlib, deflate, delta) query parameters
+func parsePixelOptions(r *http.Request) (display.PixelFormat, display.PixelEncoding, error) {
+	format, err := display.ParsePixelFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		return "", "", err
+	}
+	encoding, err := display.ParsePixelEncoding(r.URL.Query().Get("encoding"))
+	if err != nil {
+		return "", "", err
+	}
+	return format, encoding, nil
+}
+
+// handleFrameStream pushes frames over a WebSocket whenever the display changes.
+// Each frame is sent as a JSON text message with its metadata followed by a
+// binary message with the pixels, in the format requested with ?format= and
+// ?encoding= (raw RGB by default).
+func (s *Server) handleFrameStream(w http.ResponseWriter, r *http.Request) {
+	displayID := chi.URLParam(r, "displayID")
+	if displayID == "" {
+		displayID = r.URL.Query().Get("display")
+	}
+	if displayID == "" {
+		displayID = "default"
+	}
+
+	disp := s.getDisplay(displayID)
+	if disp == nil {
+		http.Error(w, "Display not found", http.StatusNotFound)
+		return
+	}
+
+	format, encoding, err := parsePixelOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	frames, unsubscribe := disp.Subscribe()
+	defer unsubscribe()
+
+	// Read loop: handles pongs and notices when the client goes away
+	closed := make(chan struct{})
+	conn.SetReadDeadline(time.Now().Add(streamPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(streamPongTimeout))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+
+	// Send the current frame right away so clients don't wait for a change
+	if err := writeStreamFrame(conn, disp.GetFrame(), format, encoding); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-closed:
+			return
+
+		case frame := <-frames:
+			if err := writeStreamFrame(conn, frame, format, encoding); err != nil {
+				log.Printf("Frame stream to %s closed: %v", r.RemoteAddr, err)
+				return
+			}
+
+		case <-ping.C:
+			deadline := time.Now().Add(streamWriteTimeout)
+			if err := conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeStreamFrame(conn *websocket.Conn, frame *display.FrameData, format display.PixelFormat, encoding display.PixelEncoding) error {
+	pixels, err := frame.Encode(format, encoding)
+	if err != nil {
+		return err
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+
+	meta := frameMeta{
+		Width:      frame.Width,
+		Height:     frame.Height,
+		FrameCount: frame.FrameCount,
+		DelayMs:    frame.DelayMs,
+		DwellSecs:  frame.DwellSecs,
+		Brightness: frame.Brightness,
+		AppName:    frame.AppName,
+		UpdatedAt:  frame.UpdatedAt,
+		Format:     format,
+		Encoding:   encoding,
+	}
+	if err := conn.WriteJSON(meta); err != nil {
+		return err
+	}
+	return conn.WriteMessage(websocket.BinaryMessage, pixels)
+}
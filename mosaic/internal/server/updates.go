@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// How often installed community apps are checked for upstream changes
+const updateCheckInterval = 6 * time.Hour
+
+// checkAppUpdates checks for app updates now and then every
+// updateCheckInterval
+func (s *Server) checkAppUpdates() {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		if updates, err := s.apps.CheckUpdates(ctx); err != nil {
+			log.Printf("Checking for app updates failed: %v", err)
+		} else if len(updates) > 0 {
+			log.Printf("App updates available: %v", updates)
+		}
+		cancel()
+
+		time.Sleep(updateCheckInterval)
+	}
+}
+
+// handleCheckAppUpdates checks for app updates right away
+func (s *Server) handleCheckAppUpdates(w http.ResponseWriter, r *http.Request) {
+	if s.apps == nil {
+		http.Error(w, "App repository not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	updates, err := s.apps.CheckUpdates(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if updates == nil {
+		updates = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"updates": updates})
+}
+
+// handlePreviewAppUpdate shows the source diff an update would apply. It's a
+// POST since the latest version is downloaded to compare it.
+func (s *Server) handlePreviewAppUpdate(w http.ResponseWriter, r *http.Request) {
+	if s.apps == nil {
+		http.Error(w, "App repository not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	appID := chi.URLParam(r, "appID")
+	if s.apps.Get(appID) == nil {
+		http.Error(w, "App not found", http.StatusNotFound)
+		return
+	}
+
+	update, err := s.apps.PreviewUpdate(r.Context(), appID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"update":       update,
+		"can_rollback": s.apps.HasPreviousVersion(appID),
+	})
+}
+
+// handleUpdateApp installs the latest version of a community app
+func (s *Server) handleUpdateApp(w http.ResponseWriter, r *http.Request) {
+	if s.apps == nil {
+		http.Error(w, "App repository not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	appID := chi.URLParam(r, "appID")
+	if s.apps.Get(appID) == nil {
+		http.Error(w, "App not found", http.StatusNotFound)
+		return
+	}
+
+	update, err := s.apps.Update(r.Context(), appID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	s.clearAppRenders()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "ok",
+		"app":    s.apps.Get(appID),
+		"update": update,
+	})
+}
+
+// handleRollbackApp returns an app to the version before its last update
+func (s *Server) handleRollbackApp(w http.ResponseWriter, r *http.Request) {
+	if s.apps == nil {
+		http.Error(w, "App repository not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	appID := chi.URLParam(r, "appID")
+	if s.apps.Get(appID) == nil {
+		http.Error(w, "App not found", http.StatusNotFound)
+		return
+	}
+
+	update, err := s.apps.Rollback(appID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.clearAppRenders()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "ok",
+		"app":    s.apps.Get(appID),
+		"update": update,
+	})
+}
+
+// clearAppRenders drops cached renders after an app's source changed, so
+// displays don't keep showing the old version
+func (s *Server) clearAppRenders() {
+	if s.renderCache != nil {
+		s.renderCache.Clear()
+	}
+}